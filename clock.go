@@ -0,0 +1,197 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time used by the tracer to timestamp spans and events and to measure durations.
+type Clock interface {
+	// Now returns the current time. To make durations immune to wall clock adjustments the returned
+	// time should carry a monotonic clock reading, like the one returned by time.Now().
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+var defaultClock systemClock
+
+// NewSystemClock returns a Clock backed by time.Now().
+func NewSystemClock() Clock {
+	return &defaultClock
+}
+
+// Now implements Now() of tracing.Clock
+func (c *systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock is a Clock that only moves when told to, for deterministic tests of spans, samplers and reporters.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a fake clock that shows the given time until it is advanced.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now implements Now() of tracing.Clock
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by the given duration.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to the given time.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Timestamp converts a clock reading to the form expected in TimeOption.Timestamp: wall time without
+// the monotonic clock reading, truncated to microseconds.
+func Timestamp(t time.Time) time.Time {
+	return t.Truncate(time.Microsecond)
+}
+
+type clockTracer struct {
+	tracer Tracer
+	clock  Clock
+}
+
+type clockSpan struct {
+	span  Span
+	clock Clock
+
+	// start is the raw clock reading used to calculate duration. When the start timestamp was provided
+	// externally it has no monotonic reading, and the duration is calculated from the wall time.
+	start time.Time
+}
+
+// NewClockTracer creates a tracer that delegates to the given tracer, but captures all times itself
+// using the given clock. Start timestamps of spans and timestamps of events that are not provided by
+// the caller are read from the clock and passed explicitly in TimeOption.Timestamp, and the duration
+// of spans is calculated from the clock readings and passed in EndOptions.Duration. With the system
+// clock the durations are based on the monotonic clock, while timestamps show the wall time.
+func NewClockTracer(tracer Tracer, clock Clock) Tracer {
+	return &clockTracer{tracer: tracer, clock: clock}
+}
+
+// BeginTrace implements BeginTrace() of tracing.Tracer
+func (t *clockTracer) BeginTrace(spanName string, service *Endpoint, options *BeginOptions) Span {
+	start, options := beginWithClock(t.clock, options)
+	return &clockSpan{span: t.tracer.BeginTrace(spanName, service, options), clock: t.clock, start: start}
+}
+
+// JoinTrace implements JoinTrace() of tracing.Tracer
+func (t *clockTracer) JoinTrace(spanName string, service *Endpoint, spanID SpanID, options *BeginOptions) Span {
+	start, options := beginWithClock(t.clock, options)
+	return &clockSpan{span: t.tracer.JoinTrace(spanName, service, spanID, options), clock: t.clock, start: start}
+}
+
+func (t *clockTracer) wrappedTracer() Tracer {
+	return t.tracer
+}
+
+// GetStringPickler implements GetStringPickler() of tracing.Tracer
+func (t *clockTracer) GetStringPickler() StringPickler {
+	return t.tracer.GetStringPickler()
+}
+
+// Close implements Close() of tracing.Tracer
+func (t *clockTracer) Close() {
+	t.tracer.Close()
+}
+
+// beginWithClock returns the start time of a span and a copy of the options with the start timestamp set.
+func beginWithClock(clock Clock, options *BeginOptions) (time.Time, *BeginOptions) {
+	opts := BeginOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Timestamp != nil {
+		return *opts.Timestamp, &opts
+	}
+	start := clock.Now()
+	ts := Timestamp(start)
+	opts.Timestamp = &ts
+	return start, &opts
+}
+
+// -----
+
+// SpanID implements SpanID() of tracing.Span
+func (s *clockSpan) SpanID() SpanID {
+	return s.span.SpanID()
+}
+
+// BeginChildSpan implements BeginChildSpan() of tracing.Span
+func (s *clockSpan) BeginChildSpan(name string, options *BeginOptions) Span {
+	start, options := beginWithClock(s.clock, options)
+	return &clockSpan{span: s.span.BeginChildSpan(name, options), clock: s.clock, start: start}
+}
+
+// End implements End() of tracing.Span
+func (s *clockSpan) End(options *EndOptions) {
+	opts := EndOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Duration == nil {
+		duration := s.clock.Now().Sub(s.start).Truncate(time.Microsecond)
+		opts.Duration = &duration
+	}
+	s.span.End(&opts)
+}
+
+// AddAttribute implements AddAttribute() of tracing.Span
+func (s *clockSpan) AddAttribute(name string, value interface{}) {
+	s.span.AddAttribute(name, value)
+}
+
+// AddEvent implements AddEvent() of tracing.Span
+func (s *clockSpan) AddEvent(name string, options *EventOptions) {
+	opts := EventOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Timestamp == nil {
+		ts := Timestamp(s.clock.Now())
+		opts.Timestamp = &ts
+	}
+	s.span.AddEvent(name, &opts)
+}
+
+func (s *clockSpan) wrappedSpan() Span {
+	return s.span
+}
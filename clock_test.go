@@ -0,0 +1,107 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+func TestSystemClock(t *testing.T) {
+	clock := tracing.NewSystemClock()
+	start := clock.Now()
+	assert.False(t, start.After(clock.Now()))
+
+	ts := tracing.Timestamp(start)
+	assert.Equal(t, 0, ts.Nanosecond()%1000)
+	// Timestamp() strips the monotonic reading, which shows up in String()
+	assert.NotContains(t, ts.String(), "m=")
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2015, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := tracing.NewFakeClock(start)
+	assert.Equal(t, start, clock.Now())
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), clock.Now())
+	clock.Set(start)
+	assert.Equal(t, start, clock.Now())
+}
+
+func TestClockTracer(t *testing.T) {
+	start := time.Date(2015, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := tracing.NewFakeClock(start)
+	mock := mocktracer.New()
+	tracer := tracing.NewClockTracer(mock, clock)
+
+	span := tracer.BeginTrace("root", endpoint, &tracing.BeginOptions{LocalComponent: "lc"})
+	clock.Advance(time.Millisecond + 500)
+	child := span.BeginChildSpan("child", nil)
+	clock.Advance(2 * time.Millisecond)
+	child.AddEvent("event", nil)
+	child.End(nil)
+	clock.Advance(time.Millisecond)
+	span.End(nil)
+
+	spans := mock.Spans()
+	require.Len(t, spans, 2)
+	root, recorded := spans[0], spans[1]
+
+	assert.Equal(t, "lc", root.Options.LocalComponent)
+	assert.Equal(t, start, *root.Options.Timestamp)
+	assert.Equal(t, 4*time.Millisecond, *root.EndOptions().Duration)
+
+	// microseconds precision
+	assert.Equal(t, start.Add(time.Millisecond), *recorded.Options.Timestamp)
+	assert.Equal(t, start.Add(3*time.Millisecond), *recorded.Events()[0].Options.Timestamp)
+	assert.Equal(t, 2*time.Millisecond, *recorded.EndOptions().Duration)
+}
+
+func TestClockTracerExternalTimes(t *testing.T) {
+	now := time.Date(2015, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := tracing.NewFakeClock(now)
+	mock := mocktracer.New()
+	tracer := tracing.NewClockTracer(mock, clock)
+
+	external := now.Add(-time.Minute)
+	duration := time.Second
+	options := &tracing.BeginOptions{TimeOption: tracing.TimeOption{Timestamp: &external}}
+	span := tracer.JoinTrace("server", endpoint, mock.CreateSpanID(1, 2, 0, 1), options)
+	span.AddEvent("event", &tracing.EventOptions{TimeOption: tracing.TimeOption{Timestamp: &external}})
+	child := span.BeginChildSpan("child", options)
+	child.End(&tracing.EndOptions{Duration: &duration})
+	span.End(nil)
+
+	spans := mock.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, external, *spans[0].Options.Timestamp)
+	assert.Equal(t, external, *spans[0].Events()[0].Options.Timestamp)
+	assert.Equal(t, time.Minute, *spans[0].EndOptions().Duration)
+	assert.Equal(t, duration, *spans[1].EndOptions().Duration)
+
+	// caller's options must not be modified
+	assert.Equal(t, &external, options.Timestamp)
+}
@@ -0,0 +1,588 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+const tracingPath = "github.com/uber-common/opentracing-go"
+
+type options struct {
+	// component is the LocalComponent of the spans; defaults to the interface name.
+	component string
+
+	// exclude is the path of a file that is not parsed, typically the previous output of the generator.
+	exclude string
+}
+
+type param struct {
+	Name string
+	Type string
+}
+
+type method struct {
+	Name     string
+	Params   []param
+	Results  []param
+	Variadic bool
+
+	// Context is set for methods taking a context.Context as the first parameter.
+	Context bool
+
+	// Error is set for methods returning an error as the last result.
+	Error bool
+}
+
+// Args returns the arguments passing the parameters to the wrapped implementation.
+func (m *method) Args() string {
+	args := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		args[i] = p.Name
+	}
+	if m.Variadic {
+		args[len(args)-1] += "..."
+	}
+	return strings.Join(args, ", ")
+}
+
+// Signature returns the parameters and results of the method.
+func (m *method) Signature() string {
+	params := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		params[i] = p.Name + " " + p.Type
+	}
+	results := make([]string, len(m.Results))
+	for i, r := range m.Results {
+		results[i] = strings.TrimSpace(r.Name + " " + r.Type)
+	}
+	s := "(" + strings.Join(params, ", ") + ")"
+	if len(results) == 1 && m.Results[0].Name == "" {
+		return s + " " + results[0]
+	} else if len(results) > 0 {
+		return s + " (" + strings.Join(results, ", ") + ")"
+	}
+	return s
+}
+
+type generator struct {
+	fset  *token.FileSet
+	files []*ast.File
+
+	// imports maps the package qualifiers used in the method signatures to import paths.
+	imports map[string]string
+
+	// importer type-checks the packages declaring embedded interfaces, created on first use.
+	importer types.Importer
+}
+
+// generate returns the source of the decorator for the interface typeName declared in the package in dir.
+func generate(dir string, typeName string, opts *options) ([]byte, error) {
+	g := &generator{fset: token.NewFileSet(), imports: make(map[string]string)}
+	if err := g.parse(dir, opts.exclude); err != nil {
+		return nil, err
+	}
+	iface, file, err := g.lookup(typeName)
+	if err != nil {
+		return nil, err
+	}
+	methods, err := g.methods(iface, file, map[string]bool{typeName: true}, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+
+	component := opts.component
+	if component == "" {
+		component = typeName
+	}
+	data := struct {
+		Package     string
+		Imports     [][]string
+		Interface   string
+		Struct      string
+		Constructor string
+		Component   string
+		Methods     []*method
+	}{
+		Package:     file.Name.Name,
+		Interface:   typeName,
+		Struct:      "traced" + exported(typeName),
+		Constructor: "NewTraced" + exported(typeName),
+		Component:   component,
+		Methods:     methods,
+	}
+	if !ast.IsExported(typeName) {
+		data.Constructor = "newTraced" + exported(typeName)
+	}
+	for _, m := range methods {
+		if !m.Context {
+			continue
+		}
+		if p, ok := g.imports["tracing"]; ok && p != tracingPath {
+			return nil, fmt.Errorf("package qualifier tracing refers to %s, which conflicts with %s", p, tracingPath)
+		}
+		g.imports["tracing"] = tracingPath
+	}
+	data.Imports = g.importGroups()
+
+	var buf bytes.Buffer
+	if err := decoratorTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return src, nil
+}
+
+func (g *generator) parse(dir string, exclude string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") || (exclude != "" && sameFile(p, exclude)) {
+			continue
+		}
+		file, err := parser.ParseFile(g.fset, p, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		g.files = append(g.files, file)
+	}
+	if len(g.files) == 0 {
+		return fmt.Errorf("no Go files found in %s", dir)
+	}
+	return nil
+}
+
+func sameFile(a, b string) bool {
+	ia, errA := os.Stat(a)
+	ib, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(ia, ib)
+}
+
+// lookup finds the interface type declared in the package and the file that declares it.
+func (g *generator) lookup(typeName string) (*ast.InterfaceType, *ast.File, error) {
+	for _, file := range g.files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if ts.Name.Name != typeName {
+					continue
+				}
+				iface, ok := ts.Type.(*ast.InterfaceType)
+				if !ok {
+					return nil, nil, fmt.Errorf("type %s is not an interface", typeName)
+				}
+				if ts.TypeParams != nil {
+					return nil, nil, fmt.Errorf("generic interface %s is not supported", typeName)
+				}
+				return iface, file, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("type %s not found", typeName)
+}
+
+// methods returns the methods of the interface, including those of embedded interfaces declared in the package
+// or imported from other packages. Methods already in seen, e.g. declared by two embedded interfaces, are skipped.
+func (g *generator) methods(iface *ast.InterfaceType, file *ast.File, visited map[string]bool, seen map[string]bool) ([]*method, error) {
+	var methods []*method
+	for _, field := range iface.Methods.List {
+		switch t := field.Type.(type) {
+		case *ast.FuncType:
+			name := field.Names[0].Name
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			m, err := g.method(name, t, file)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, m)
+		case *ast.Ident:
+			if visited[t.Name] {
+				continue
+			}
+			visited[t.Name] = true
+			embedded, embeddedFile, err := g.lookup(t.Name)
+			if err != nil {
+				return nil, err
+			}
+			embeddedMethods, err := g.methods(embedded, embeddedFile, visited, seen)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, embeddedMethods...)
+		case *ast.SelectorExpr:
+			embeddedMethods, err := g.importedMethods(t, file, seen)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, embeddedMethods...)
+		default:
+			return nil, fmt.Errorf("embedded type %s is not supported, only interfaces", g.render(field.Type))
+		}
+	}
+	return methods, nil
+}
+
+// importedMethods returns the methods of an interface declared in another package, which is type-checked
+// from source. Methods already in seen are skipped.
+func (g *generator) importedMethods(sel *ast.SelectorExpr, file *ast.File, seen map[string]bool) ([]*method, error) {
+	name := g.render(sel)
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("embedded type %s is not supported, only interfaces", name)
+	}
+	p, ok := lookupImport(x.Name, file)
+	if !ok {
+		return nil, fmt.Errorf("cannot resolve package qualifier %s", x.Name)
+	}
+	if g.importer == nil {
+		g.importer = importer.ForCompiler(g.fset, "source", nil)
+	}
+	pkg, err := g.importer.Import(p)
+	if err != nil {
+		return nil, fmt.Errorf("loading embedded type %s: %v", name, err)
+	}
+	obj, ok := pkg.Scope().Lookup(sel.Sel.Name).(*types.TypeName)
+	if !ok || !obj.Exported() {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok || !iface.IsMethodSet() {
+		return nil, fmt.Errorf("embedded type %s is not an interface", name)
+	}
+
+	var methods []*method
+	for i := 0; i < iface.NumMethods(); i++ {
+		fn := iface.Method(i)
+		if !fn.Exported() {
+			return nil, fmt.Errorf("embedded type %s has unexported method %s", name, fn.Name())
+		}
+		if seen[fn.Name()] {
+			continue
+		}
+		seen[fn.Name()] = true
+		m, err := g.signatureMethod(fn.Name(), fn.Type().(*types.Signature))
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, m)
+	}
+	return methods, nil
+}
+
+func (g *generator) method(name string, fn *ast.FuncType, file *ast.File) (*method, error) {
+	m := &method{Name: name}
+	for _, field := range fn.Params.List {
+		typ, err := g.typeOf(field.Type, file)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := field.Type.(*ast.Ellipsis); ok {
+			m.Variadic = true
+		}
+		if len(field.Names) == 0 {
+			m.Params = append(m.Params, param{Type: typ})
+		}
+		for _, n := range field.Names {
+			m.Params = append(m.Params, param{Name: n.Name, Type: typ})
+		}
+	}
+	if fn.Results != nil {
+		for _, field := range fn.Results.List {
+			typ, err := g.typeOf(field.Type, file)
+			if err != nil {
+				return nil, err
+			}
+			for i := 0; i < max(1, len(field.Names)); i++ {
+				m.Results = append(m.Results, param{Type: typ})
+			}
+		}
+	}
+	m.Context = len(fn.Params.List) > 0 && isContext(fn.Params.List[0].Type, file)
+	m.resolveNames()
+	return m, nil
+}
+
+// signatureMethod is the counterpart of method() for the type-checked methods of imported interfaces.
+func (g *generator) signatureMethod(name string, sig *types.Signature) (*method, error) {
+	var err error
+	qualifier := func(pkg *types.Package) string {
+		if useErr := g.use(pkg.Name(), pkg.Path()); useErr != nil && err == nil {
+			err = useErr
+		}
+		return pkg.Name()
+	}
+
+	m := &method{Name: name, Variadic: sig.Variadic()}
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		v := params.At(i)
+		typ := types.TypeString(v.Type(), qualifier)
+		if m.Variadic && i == params.Len()-1 {
+			typ = "..." + types.TypeString(v.Type().(*types.Slice).Elem(), qualifier)
+		}
+		m.Params = append(m.Params, param{Name: v.Name(), Type: typ})
+	}
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		m.Results = append(m.Results, param{Type: types.TypeString(results.At(i).Type(), qualifier)})
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.Context = params.Len() > 0 && isContextType(params.At(0).Type())
+	m.resolveNames()
+	return m, nil
+}
+
+// resolveNames sets Error and names the parameters and results as the decorator needs them.
+func (m *method) resolveNames() {
+	m.Error = len(m.Results) > 0 && m.Results[len(m.Results)-1].Type == "error"
+
+	// Results are named in methods that record the error, so that the deferred function can see it.
+	// Parameters are renamed if they are missing or conflict with the names used by the decorator.
+	reserved := map[string]bool{"_": true, "": true, "t": true, "done": true, "err": true, "tracing": true}
+	if m.Context && m.Error {
+		for i := range m.Results {
+			m.Results[i].Name = "r" + strconv.Itoa(i)
+			reserved[m.Results[i].Name] = true
+		}
+		m.Results[len(m.Results)-1].Name = "err"
+	}
+	renamedContext := m.Context && reserved[m.Params[0].Name]
+	if renamedContext {
+		m.Params[0].Name = "ctx"
+		reserved["ctx"] = true
+	}
+	for i := range m.Params {
+		if (i > 0 || !renamedContext) && reserved[m.Params[i].Name] {
+			m.Params[i].Name = "p" + strconv.Itoa(i)
+		}
+	}
+}
+
+// isContext reports whether the expression denotes context.Context in the file.
+func isContext(expr ast.Expr, file *ast.File) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Context" {
+		return false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	for _, spec := range file.Imports {
+		if importPath(spec) == "context" {
+			return importName(spec) == x.Name
+		}
+	}
+	return false
+}
+
+// isContextType reports whether the type is context.Context.
+func isContextType(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "context" && obj.Name() == "Context"
+}
+
+// typeOf renders the type expression and records the imports it refers to.
+func (g *generator) typeOf(expr ast.Expr, file *ast.File) (string, error) {
+	var err error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok || err != nil {
+			return err == nil
+		}
+		if x, ok := sel.X.(*ast.Ident); ok {
+			err = g.resolve(x.Name, file)
+		}
+		return false
+	})
+	if err != nil {
+		return "", err
+	}
+	return g.render(expr), nil
+}
+
+func (g *generator) resolve(qualifier string, file *ast.File) error {
+	p, ok := lookupImport(qualifier, file)
+	if !ok {
+		return fmt.Errorf("cannot resolve package qualifier %s", qualifier)
+	}
+	return g.use(qualifier, p)
+}
+
+// use records the import of the package p under the qualifier in the generated file.
+func (g *generator) use(qualifier, p string) error {
+	if previous, ok := g.imports[qualifier]; ok && previous != p {
+		return fmt.Errorf("package qualifier %s refers to both %s and %s", qualifier, previous, p)
+	}
+	g.imports[qualifier] = p
+	return nil
+}
+
+// lookupImport returns the import path of the package qualifier in the file.
+func lookupImport(qualifier string, file *ast.File) (string, bool) {
+	for _, spec := range file.Imports {
+		if importName(spec) == qualifier {
+			return importPath(spec), true
+		}
+	}
+	return "", false
+}
+
+func (g *generator) render(expr ast.Expr) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, g.fset, expr)
+	return buf.String()
+}
+
+// importGroups returns the imports of the generated file as standard library and other groups,
+// each holding the import specs.
+func (g *generator) importGroups() [][]string {
+	var std, other []string
+	for name, p := range g.imports {
+		spec := strconv.Quote(p)
+		if name != defaultImportName(p) {
+			spec = name + " " + spec
+		}
+		if strings.Contains(strings.Split(p, "/")[0], ".") {
+			other = append(other, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	var groups [][]string
+	for _, group := range [][]string{std, other} {
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+var versionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+func importPath(spec *ast.ImportSpec) string {
+	p, _ := strconv.Unquote(spec.Path.Value)
+	return p
+}
+
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	return defaultImportName(importPath(spec))
+}
+
+// defaultImportName guesses the package name from the import path, following the common conventions
+// for major version suffixes and go- prefixes.
+func defaultImportName(p string) string {
+	if p == tracingPath {
+		return "tracing"
+	}
+	name := path.Base(p)
+	if versionSuffix.MatchString(name) && path.Dir(p) != "." {
+		name = path.Base(path.Dir(p))
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func exported(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+var decoratorTemplate = template.Must(template.New("decorator").Parse(`// Code generated by tracegen. DO NOT EDIT.
+
+package {{.Package}}
+{{if .Imports}}
+import (
+{{- range .Imports}}
+{{range .}}	{{.}}
+{{end}}{{end -}}
+)
+{{end}}
+// {{.Struct}} decorates {{.Interface}} with tracing.
+type {{.Struct}} struct {
+	next {{.Interface}}
+}
+
+// {{.Constructor}} returns a {{.Interface}} that traces the calls of the methods of next taking a context
+// in spans named "{{.Interface}}.<Method>". The spans are children of the span in the context, if any.
+func {{.Constructor}}(next {{.Interface}}) {{.Interface}} {
+	return &{{.Struct}}{next: next}
+}
+{{range .Methods}}
+// {{.Name}} implements {{.Name}}() of {{$.Interface}}
+func (t *{{$.Struct}}) {{.Name}}{{.Signature}} {
+{{- if .Context}}
+	{{(index .Params 0).Name}}, done := tracing.Trace({{(index .Params 0).Name}}, "{{$.Interface}}.{{.Name}}", &tracing.BeginOptions{LocalComponent: {{printf "%q" $.Component}}})
+	defer done({{if .Error}}&err{{else}}nil{{end}})
+{{- end}}
+	{{if .Results}}return {{end}}t.next.{{.Name}}({{.Args}})
+}
+{{end}}`))
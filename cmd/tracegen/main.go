@@ -0,0 +1,71 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Command tracegen generates tracing decorators for interfaces. It is meant to be run by go generate:
+//
+//	//go:generate go run github.com/uber-common/opentracing-go/cmd/tracegen -type Repository
+//
+// For the interface Repository, the generated file repository_tracing.go declares NewTracedRepository(),
+// which wraps an implementation of the interface. Every method taking a context.Context as its first
+// parameter runs in a span named "Repository.Method", started by tracing.Trace() as a child of the span
+// in the context, with LocalComponent set and the returned error recorded in EndOptions.Error.
+// Other methods are delegated to the wrapped implementation unchanged.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeName := flag.String("type", "", "name of the interface to decorate; mandatory")
+	component := flag.String("component", "", "LocalComponent of the spans; defaults to the interface name")
+	output := flag.String("output", "", "output file name; defaults to <type>_tracing.go")
+	flag.Parse()
+
+	if *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if args := flag.Args(); len(args) > 0 {
+		dir = args[0]
+	}
+	if *output == "" {
+		*output = strings.ToLower(*typeName) + "_tracing.go"
+	}
+	outputPath := *output
+	if !filepath.IsAbs(outputPath) {
+		outputPath = filepath.Join(dir, outputPath)
+	}
+
+	src, err := generate(dir, *typeName, &options{component: *component, exclude: outputPath})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tracegen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(outputPath, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "tracegen: %v\n", err)
+		os.Exit(1)
+	}
+}
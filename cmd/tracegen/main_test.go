@@ -0,0 +1,134 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		typeName  string
+		component string
+		golden    string
+	}{
+		{typeName: "Repository", golden: "repository_tracing.go.golden"},
+		{typeName: "Pinger", component: "pinger", golden: "pinger_tracing.go.golden"},
+		{typeName: "Store", golden: "store_tracing.go.golden"},
+		{typeName: "External", golden: "external_tracing.go.golden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typeName, func(t *testing.T) {
+			src, err := generate("testdata/repository", tt.typeName, &options{component: tt.component})
+			require.NoError(t, err)
+
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				require.NoError(t, os.WriteFile(golden, src, 0644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(src))
+
+			typeCheck(t, "testdata/repository", src)
+		})
+	}
+}
+
+// typeCheck verifies that the generated source compiles together with the package it was generated from.
+func typeCheck(t *testing.T, dir string, src []byte) {
+	fset := token.NewFileSet()
+	files := parseDir(t, fset, dir)
+	generated, err := parser.ParseFile(fset, "generated.go", src, 0)
+	require.NoError(t, err)
+
+	conf := types.Config{Importer: &testImporter{fset: fset, t: t, std: importer.Default()}}
+	_, err = conf.Check("repository", fset, append(files, generated), nil)
+	require.NoError(t, err, string(src))
+}
+
+func parseDir(t *testing.T, fset *token.FileSet, dir string) []*ast.File {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	require.NoError(t, err)
+	var files []*ast.File
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, p, nil, 0)
+		require.NoError(t, err)
+		files = append(files, file)
+	}
+	return files
+}
+
+// testImporter type-checks the tracing package from the source of this repository, and imports
+// the standard library packages with the default importer.
+type testImporter struct {
+	fset    *token.FileSet
+	t       *testing.T
+	std     types.Importer
+	tracing *types.Package
+}
+
+func (i *testImporter) Import(path string) (*types.Package, error) {
+	if path != tracingPath {
+		return i.std.Import(path)
+	}
+	if i.tracing == nil {
+		conf := types.Config{Importer: i}
+		pkg, err := conf.Check(tracingPath, i.fset, parseDir(i.t, i.fset, "../.."), nil)
+		if err != nil {
+			return nil, err
+		}
+		i.tracing = pkg
+	}
+	return i.tracing, nil
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		typeName string
+		err      string
+	}{
+		{typeName: "Missing", err: "type Missing not found"},
+		{typeName: "NotAnInterface", err: "type NotAnInterface is not an interface"},
+		{typeName: "Constraint", err: "embedded type time.Time is not an interface"},
+	}
+	for _, tt := range tests {
+		_, err := generate("testdata/repository", tt.typeName, &options{})
+		assert.EqualError(t, err, tt.err, tt.typeName)
+	}
+}
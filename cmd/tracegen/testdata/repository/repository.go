@@ -0,0 +1,85 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"time"
+)
+
+type Item struct {
+	ID   string
+	Data []byte
+}
+
+type Event struct {
+	Item *Item
+	At   time.Time
+}
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type Repository interface {
+	Pinger
+
+	Load(ctx context.Context, id string) (*Item, error)
+	Save(ctx context.Context, items ...*Item) error
+	Count(context.Context) (int, error)
+	Export(ctx context.Context, w io.Writer, t time.Time) (n int64, err error)
+	Watch(ctx context.Context, buffer int) <-chan Event
+	Name() string
+	Reset()
+}
+
+type NotAnInterface struct{}
+
+type External interface {
+	io.ReadCloser
+	driver.ExecerContext
+
+	Close() error
+	Name() string
+}
+
+type Constraint interface {
+	time.Time
+}
+
+type Reader interface {
+	Get(ctx context.Context, id string) (*Item, error)
+}
+
+type Writer interface {
+	Get(ctx context.Context, id string) (*Item, error)
+	Put(ctx context.Context, item *Item) error
+}
+
+type Store interface {
+	Reader
+	Writer
+
+	Do(_ context.Context, ctx string) error
+	Use(t context.Context, done bool, err string) (int, error)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Names of the attribute and events recorded by WatchContext().
+const (
+	// ContextDeadlineKey is the attribute holding the context deadline in RFC 3339 format, microseconds precision.
+	ContextDeadlineKey = "context.deadline"
+
+	// ContextCanceledEvent is the event recorded when the context is canceled.
+	ContextCanceledEvent = "context.canceled"
+
+	// ContextDeadlineExceededEvent is the event recorded when the context deadline passes.
+	ContextDeadlineExceededEvent = "context.deadline_exceeded"
+)
+
+type contextSpan struct {
+	Span
+	ctx  context.Context
+	stop func() bool
+
+	mu    sync.Mutex
+	ended bool
+}
+
+// WatchContext binds the span to the context, so that timeouts and cancellations show up in the trace.
+// The context deadline, if any, is recorded as the ContextDeadlineKey attribute. When the context is done
+// before the span ends, an event is added to the span with the time of the cancellation, and the returned
+// span sets EndOptions.Error to the cause of the cancellation on End(), unless the error is already set.
+func WatchContext(ctx context.Context, span Span) Span {
+	if deadline, ok := ctx.Deadline(); ok {
+		span.AddAttribute(ContextDeadlineKey, Timestamp(deadline).Format(time.RFC3339Nano))
+	}
+	s := &contextSpan{Span: span, ctx: ctx}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop = context.AfterFunc(ctx, s.contextDone)
+	return s
+}
+
+func (s *contextSpan) contextDone() {
+	ts := Timestamp(time.Now())
+	event := ContextCanceledEvent
+	if errors.Is(s.ctx.Err(), context.DeadlineExceeded) {
+		event = ContextDeadlineExceededEvent
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.Span.AddEvent(event, &EventOptions{TimeOption: TimeOption{Timestamp: &ts}})
+	}
+}
+
+// End implements End() of tracing.Span
+func (s *contextSpan) End(options *EndOptions) {
+	s.mu.Lock()
+	s.ended = true
+	s.stop()
+	s.mu.Unlock()
+
+	if err := s.ctx.Err(); err != nil && (options == nil || options.Error == nil) {
+		opts := EndOptions{}
+		if options != nil {
+			opts = *options
+		}
+		opts.Error = context.Cause(s.ctx)
+		options = &opts
+	}
+	s.Span.End(options)
+}
+
+func (s *contextSpan) wrappedSpan() Span {
+	return s.Span
+}
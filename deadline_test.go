@@ -0,0 +1,99 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+// waitForEvents polls the span until it has the expected number of events, since they are added asynchronously.
+func waitForEvents(span *mocktracer.Span, n int) []mocktracer.Event {
+	for i := 0; i < 100 && len(span.Events()) < n; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return span.Events()
+}
+
+func TestWatchContextDeadline(t *testing.T) {
+	tracer := mocktracer.New()
+	deadline := time.Now().Add(20 * time.Millisecond)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	span := tracing.WatchContext(ctx, tracer.BeginTrace("root", endpoint, nil))
+	recorded := tracer.Spans()[0]
+	value, ok := recorded.Attribute(tracing.ContextDeadlineKey)
+	assert.True(t, ok)
+	assert.Equal(t, tracing.Timestamp(deadline).Format(time.RFC3339Nano), value)
+
+	events := waitForEvents(recorded, 1)
+	require.Len(t, events, 1)
+	assert.Equal(t, tracing.ContextDeadlineExceededEvent, events[0].Name)
+	assert.False(t, events[0].Options.Timestamp.Before(tracing.Timestamp(deadline)))
+
+	span.End(nil)
+	assert.Equal(t, context.DeadlineExceeded, recorded.EndOptions().Error)
+}
+
+func TestWatchContextCanceled(t *testing.T) {
+	tracer := mocktracer.New()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	span := tracing.WatchContext(ctx, tracer.BeginTrace("root", endpoint, nil))
+	recorded := tracer.Spans()[0]
+	_, ok := recorded.Attribute(tracing.ContextDeadlineKey)
+	assert.False(t, ok)
+
+	cause := errors.New("client went away")
+	cancel(cause)
+	events := waitForEvents(recorded, 1)
+	require.Len(t, events, 1)
+	assert.Equal(t, tracing.ContextCanceledEvent, events[0].Name)
+
+	// the explicit error is preserved
+	explicit := errors.New("explicit")
+	span.End(&tracing.EndOptions{Error: explicit})
+	assert.Equal(t, explicit, recorded.EndOptions().Error)
+
+	span = tracing.WatchContext(ctx, tracer.BeginTrace("late", endpoint, nil))
+	span.End(nil)
+	assert.Equal(t, cause, tracer.Spans()[1].EndOptions().Error)
+}
+
+func TestWatchContextEndedBeforeCancel(t *testing.T) {
+	tracer := mocktracer.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	span := tracing.WatchContext(ctx, tracer.BeginTrace("root", endpoint, nil))
+	span.End(nil)
+	cancel()
+
+	time.Sleep(10 * time.Millisecond)
+	recorded := tracer.Spans()[0]
+	assert.Empty(t, recorded.Events())
+	assert.NoError(t, recorded.EndOptions().Error)
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+)
+
+// FindingKind classifies a span lifecycle problem detected by the debug tracer.
+type FindingKind int
+
+const (
+	// DoubleEnd is reported when End() is called on a span that has already ended.
+	DoubleEnd FindingKind = iota + 1
+
+	// UseAfterEnd is reported when AddAttribute(), AddEvent() or BeginChildSpan() is called on an ended span.
+	UseAfterEnd
+
+	// LeakedSpan is reported when a span is garbage-collected without End() ever being called.
+	LeakedSpan
+
+	// UnfinishedSpan is reported when a span is still open at the time the tracer is closed.
+	UnfinishedSpan
+)
+
+// String returns a human readable name of the finding kind.
+func (k FindingKind) String() string {
+	switch k {
+	case DoubleEnd:
+		return "double End"
+	case UseAfterEnd:
+		return "use after End"
+	case LeakedSpan:
+		return "span garbage-collected without End"
+	case UnfinishedSpan:
+		return "span not ended before tracer Close"
+	default:
+		return fmt.Sprintf("FindingKind(%d)", int(k))
+	}
+}
+
+// Finding describes a misuse of the span lifecycle detected by the debug tracer.
+type Finding struct {
+	// Kind is the type of the detected problem.
+	Kind FindingKind
+
+	// SpanName is the name the span was created with.
+	SpanName string
+
+	// Operation is the span method that was called incorrectly, e.g. "AddAttribute".
+	// It is empty for leaked and unfinished spans.
+	Operation string
+
+	// CreatedAt is the call site (file:line) that created the span.
+	CreatedAt string
+
+	// CalledAt is the call site (file:line) of the offending method call.
+	// It is empty for leaked and unfinished spans.
+	CalledAt string
+}
+
+// String returns a single-line description of the finding.
+func (f Finding) String() string {
+	if f.Operation == "" {
+		return fmt.Sprintf("%s: span %q created at %s", f.Kind, f.SpanName, f.CreatedAt)
+	}
+	return fmt.Sprintf("%s: %s on span %q at %s, span created at %s",
+		f.Kind, f.Operation, f.SpanName, f.CalledAt, f.CreatedAt)
+}
+
+// DebugOptions contains optional settings that can be passed to NewDebugTracer().
+type DebugOptions struct {
+	// OnFinding is called for every detected problem. It may be called concurrently, including from
+	// the finalizer goroutine. If nil, findings are written to the standard logger.
+	OnFinding func(Finding)
+}
+
+type debugTracer struct {
+	tracer    Tracer
+	onFinding func(Finding)
+
+	mu   sync.Mutex
+	open map[*debugSpanState]struct{}
+}
+
+type debugSpan struct {
+	span   Span
+	tracer *debugTracer
+	state  *debugSpanState
+}
+
+// debugSpanState is kept separately from debugSpan so that the tracer can track open spans
+// without keeping them reachable, which would prevent the detection of leaked spans.
+type debugSpanState struct {
+	name      string
+	createdAt string
+
+	mu       sync.Mutex
+	ended    bool
+	reported bool
+}
+
+// NewDebugTracer creates a tracer that delegates to the given tracer and validates the lifecycle of every
+// span it creates. It detects repeated calls to End(), use of spans after End(), and spans that are
+// garbage-collected or still open at Close() without ever being ended. The debug tracer adds overhead
+// to every span and is meant for development and testing.
+func NewDebugTracer(tracer Tracer, options *DebugOptions) Tracer {
+	t := &debugTracer{
+		tracer:    tracer,
+		onFinding: defaultOnFinding,
+		open:      make(map[*debugSpanState]struct{}),
+	}
+	if options != nil && options.OnFinding != nil {
+		t.onFinding = options.OnFinding
+	}
+	return t
+}
+
+func defaultOnFinding(f Finding) {
+	log.Printf("tracing: %s", f)
+}
+
+// callSite returns file:line of the caller, skip frames above the function calling callSite.
+func callSite(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 2)
+	if !ok {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// BeginTrace implements BeginTrace() of tracing.Tracer
+func (t *debugTracer) BeginTrace(spanName string, service *Endpoint, options *BeginOptions) Span {
+	return t.wrap(spanName, callSite(0), t.tracer.BeginTrace(spanName, service, options))
+}
+
+// JoinTrace implements JoinTrace() of tracing.Tracer
+func (t *debugTracer) JoinTrace(spanName string, service *Endpoint, spanID SpanID, options *BeginOptions) Span {
+	return t.wrap(spanName, callSite(0), t.tracer.JoinTrace(spanName, service, spanID, options))
+}
+
+func (t *debugTracer) wrappedTracer() Tracer {
+	return t.tracer
+}
+
+// GetStringPickler implements GetStringPickler() of tracing.Tracer
+func (t *debugTracer) GetStringPickler() StringPickler {
+	return t.tracer.GetStringPickler()
+}
+
+// Close implements Close() of tracing.Tracer. Spans that are still open are reported as unfinished.
+func (t *debugTracer) Close() {
+	t.mu.Lock()
+	open := t.open
+	t.open = make(map[*debugSpanState]struct{})
+	t.mu.Unlock()
+
+	for state := range open {
+		state.mu.Lock()
+		report := !state.ended && !state.reported
+		state.reported = true
+		state.mu.Unlock()
+		if report {
+			t.onFinding(Finding{Kind: UnfinishedSpan, SpanName: state.name, CreatedAt: state.createdAt})
+		}
+	}
+	t.tracer.Close()
+}
+
+func (t *debugTracer) wrap(name string, createdAt string, span Span) Span {
+	state := &debugSpanState{name: name, createdAt: createdAt}
+	t.mu.Lock()
+	t.open[state] = struct{}{}
+	t.mu.Unlock()
+
+	s := &debugSpan{span: span, tracer: t, state: state}
+	runtime.SetFinalizer(s, (*debugSpan).finalize)
+	return s
+}
+
+func (t *debugTracer) forget(state *debugSpanState) {
+	t.mu.Lock()
+	delete(t.open, state)
+	t.mu.Unlock()
+}
+
+// -----
+
+// SpanID implements SpanID() of tracing.Span
+func (s *debugSpan) SpanID() SpanID {
+	return s.span.SpanID()
+}
+
+// BeginChildSpan implements BeginChildSpan() of tracing.Span
+func (s *debugSpan) BeginChildSpan(name string, options *BeginOptions) Span {
+	s.checkNotEnded("BeginChildSpan")
+	return s.tracer.wrap(name, callSite(0), s.span.BeginChildSpan(name, options))
+}
+
+// End implements End() of tracing.Span
+func (s *debugSpan) End(options *EndOptions) {
+	s.state.mu.Lock()
+	ended := s.state.ended
+	s.state.ended = true
+	s.state.mu.Unlock()
+
+	if ended {
+		s.report(DoubleEnd, "End", callSite(0))
+		return
+	}
+	s.tracer.forget(s.state)
+	s.span.End(options)
+}
+
+// AddAttribute implements AddAttribute() of tracing.Span
+func (s *debugSpan) AddAttribute(name string, value interface{}) {
+	s.checkNotEnded("AddAttribute")
+	s.span.AddAttribute(name, value)
+}
+
+// AddEvent implements AddEvent() of tracing.Span
+func (s *debugSpan) AddEvent(name string, options *EventOptions) {
+	s.checkNotEnded("AddEvent")
+	s.span.AddEvent(name, options)
+}
+
+func (s *debugSpan) wrappedSpan() Span {
+	return s.span
+}
+
+func (s *debugSpan) checkNotEnded(operation string) {
+	s.state.mu.Lock()
+	ended := s.state.ended
+	s.state.mu.Unlock()
+	if ended {
+		s.report(UseAfterEnd, operation, callSite(1))
+	}
+}
+
+func (s *debugSpan) report(kind FindingKind, operation string, calledAt string) {
+	s.tracer.onFinding(Finding{
+		Kind:      kind,
+		SpanName:  s.state.name,
+		Operation: operation,
+		CreatedAt: s.state.createdAt,
+		CalledAt:  calledAt,
+	})
+}
+
+func (s *debugSpan) finalize() {
+	s.state.mu.Lock()
+	report := !s.state.ended && !s.state.reported
+	s.state.reported = true
+	s.state.mu.Unlock()
+
+	s.tracer.forget(s.state)
+	if report {
+		s.tracer.onFinding(Finding{Kind: LeakedSpan, SpanName: s.state.name, CreatedAt: s.state.createdAt})
+	}
+}
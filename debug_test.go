@@ -0,0 +1,143 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+)
+
+type findingCollector struct {
+	mu       sync.Mutex
+	findings []tracing.Finding
+}
+
+func (c *findingCollector) collect(f tracing.Finding) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.findings = append(c.findings, f)
+}
+
+func (c *findingCollector) get() []tracing.Finding {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]tracing.Finding(nil), c.findings...)
+}
+
+func newDebugTracer() (tracing.Tracer, *findingCollector) {
+	c := &findingCollector{}
+	tracer := tracing.NewDebugTracer(tracing.NewNoopTracer(), &tracing.DebugOptions{OnFinding: c.collect})
+	return tracer, c
+}
+
+func TestDebugTracerCorrectUsage(t *testing.T) {
+	tracer, c := newDebugTracer()
+	span := tracer.BeginTrace("root", endpoint, nil)
+	child := span.BeginChildSpan("child", nil)
+	child.AddAttribute("key", "value")
+	child.AddEvent("event", nil)
+	child.End(nil)
+	span.End(nil)
+	tracer.Close()
+	assert.Empty(t, c.get())
+}
+
+func TestDebugTracerDoubleEnd(t *testing.T) {
+	tracer, c := newDebugTracer()
+	span := tracer.BeginTrace("root", endpoint, nil)
+	span.End(nil)
+	span.End(nil)
+
+	findings := c.get()
+	require.Len(t, findings, 1)
+	assert.Equal(t, tracing.DoubleEnd, findings[0].Kind)
+	assert.Equal(t, "root", findings[0].SpanName)
+	assert.Equal(t, "End", findings[0].Operation)
+	assert.Contains(t, findings[0].CreatedAt, "debug_test.go")
+	assert.Contains(t, findings[0].CalledAt, "debug_test.go")
+	assert.NotEqual(t, findings[0].CreatedAt, findings[0].CalledAt)
+}
+
+func TestDebugTracerUseAfterEnd(t *testing.T) {
+	tracer, c := newDebugTracer()
+	spanID, err := tracer.GetStringPickler().FromString("x")
+	require.NoError(t, err)
+	span := tracer.JoinTrace("server", endpoint, spanID, nil)
+	span.End(nil)
+	span.AddAttribute("key", "value")
+	span.AddEvent("event", nil)
+	span.BeginChildSpan("child", nil).End(nil)
+
+	findings := c.get()
+	require.Len(t, findings, 3)
+	for i, op := range []string{"AddAttribute", "AddEvent", "BeginChildSpan"} {
+		assert.Equal(t, tracing.UseAfterEnd, findings[i].Kind)
+		assert.Equal(t, op, findings[i].Operation)
+		assert.Contains(t, findings[i].CalledAt, "debug_test.go")
+		assert.Contains(t, findings[i].String(), op)
+	}
+}
+
+func TestDebugTracerUnfinishedAtClose(t *testing.T) {
+	tracer, c := newDebugTracer()
+	span := tracer.BeginTrace("root", endpoint, nil)
+	span.BeginChildSpan("child", nil).End(nil)
+	tracer.Close()
+
+	findings := c.get()
+	require.Len(t, findings, 1)
+	assert.Equal(t, tracing.UnfinishedSpan, findings[0].Kind)
+	assert.Equal(t, "root", findings[0].SpanName)
+	assert.Empty(t, findings[0].Operation)
+	assert.True(t, strings.HasPrefix(findings[0].String(), tracing.UnfinishedSpan.String()))
+
+	// ending the span afterwards is not a problem
+	span.End(nil)
+	assert.Len(t, c.get(), 1)
+}
+
+func TestDebugTracerLeakedSpan(t *testing.T) {
+	tracer, c := newDebugTracer()
+	func() {
+		tracer.BeginTrace("leaked", endpoint, nil)
+	}()
+
+	for i := 0; i < 50 && len(c.get()) == 0; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	findings := c.get()
+	require.Len(t, findings, 1)
+	assert.Equal(t, tracing.LeakedSpan, findings[0].Kind)
+	assert.Equal(t, "leaked", findings[0].SpanName)
+	assert.Contains(t, findings[0].CreatedAt, "debug_test.go")
+
+	// the leaked span must not be reported again
+	tracer.Close()
+	assert.Len(t, c.get(), 1)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// NoLocalAddressError is returned by LocalEndpoint() when the host has no usable network interface address.
+var NoLocalAddressError = errors.New("No non-loopback network interface address found")
+
+// EndpointFromAddr creates an endpoint from an address in "host:port" form, such as http.Request.RemoteAddr.
+// The port may be omitted. IP addresses are stored in IPv4 or IPv6 fields, host names are not resolved.
+func EndpointFromAddr(serviceName string, addr string) (*Endpoint, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// assume there is no port, e.g. "10.0.0.1" or "::1"
+		host, port = addr, ""
+	}
+	if host == "" {
+		return nil, fmt.Errorf("missing host in address %q", addr)
+	}
+	endpoint := &Endpoint{ServiceName: serviceName}
+	if port != "" {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in address %q", addr)
+		}
+		endpoint.Port = uint16(p)
+	}
+	endpoint.setIP(net.ParseIP(host))
+	return endpoint, nil
+}
+
+// EndpointFromNetAddr creates an endpoint without a service name from a network address,
+// such as the local or remote address of a net.Conn.
+func EndpointFromNetAddr(addr net.Addr) (*Endpoint, error) {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return endpointFromIPPort(a.IP, a.Port), nil
+	case *net.UDPAddr:
+		return endpointFromIPPort(a.IP, a.Port), nil
+	case *net.IPAddr:
+		return endpointFromIPPort(a.IP, 0), nil
+	case nil:
+		return nil, errors.New("nil address")
+	default:
+		return EndpointFromAddr("", addr.String())
+	}
+}
+
+// LocalEndpoint creates an endpoint for a service running on this host, using the addresses of the first
+// non-loopback network interface that is up. It returns NoLocalAddressError if there is no such interface.
+func LocalEndpoint(serviceName string, port uint16) (*Endpoint, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		endpoint := &Endpoint{ServiceName: serviceName, Port: port}
+		found := false
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			if ip4 := ipNet.IP.To4(); ip4 != nil {
+				if endpoint.IPv4 == 0 {
+					endpoint.IPv4 = ipv4ToInt32(ip4)
+				}
+			} else if !endpoint.hasIPv6() {
+				copy(endpoint.IPv6[:], ipNet.IP.To16())
+			}
+			found = true
+		}
+		if found {
+			return endpoint, nil
+		}
+	}
+	return nil, NoLocalAddressError
+}
+
+// IP returns the IP address of the endpoint, preferring IPv6 when it is known, or nil if neither is set.
+func (e *Endpoint) IP() net.IP {
+	if e.hasIPv6() {
+		ip := make(net.IP, net.IPv6len)
+		copy(ip, e.IPv6[:])
+		return ip
+	}
+	if e.IPv4 != 0 {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(e.IPv4))
+		return ip
+	}
+	return nil
+}
+
+func endpointFromIPPort(ip net.IP, port int) *Endpoint {
+	endpoint := &Endpoint{Port: uint16(port)}
+	endpoint.setIP(ip)
+	return endpoint
+}
+
+func (e *Endpoint) setIP(ip net.IP) {
+	if ip == nil {
+		return
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		e.IPv4 = ipv4ToInt32(ip4)
+	} else {
+		copy(e.IPv6[:], ip.To16())
+	}
+}
+
+func (e *Endpoint) hasIPv6() bool {
+	return e.IPv6 != [16]byte{}
+}
+
+// ipv4ToInt32 packs the address in network byte order, so addresses above 127.255.255.255 are negative.
+func ipv4ToInt32(ip4 net.IP) int32 {
+	return int32(binary.BigEndian.Uint32(ip4))
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+)
+
+func ipv6(addr string) [16]byte {
+	var ip [16]byte
+	copy(ip[:], net.ParseIP(addr))
+	return ip
+}
+
+func TestEndpointFromAddr(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected *tracing.Endpoint
+	}{
+		{"127.0.0.1:8080", &tracing.Endpoint{ServiceName: "svc", IPv4: 127<<24 | 1, Port: 8080}},
+		{"192.168.1.2:80", &tracing.Endpoint{ServiceName: "svc", IPv4: -1062731518, Port: 80}},
+		{"255.255.255.255:1", &tracing.Endpoint{ServiceName: "svc", IPv4: -1, Port: 1}},
+		{"10.0.0.1", &tracing.Endpoint{ServiceName: "svc", IPv4: 10<<24 | 1}},
+		{"[::1]:443", &tracing.Endpoint{ServiceName: "svc", IPv6: ipv6("::1"), Port: 443}},
+		{"fe80::1", &tracing.Endpoint{ServiceName: "svc", IPv6: ipv6("fe80::1")}},
+		{"example.com:25", &tracing.Endpoint{ServiceName: "svc", Port: 25}},
+	}
+	for _, test := range tests {
+		endpoint, err := tracing.EndpointFromAddr("svc", test.addr)
+		require.NoError(t, err, test.addr)
+		assert.Equal(t, test.expected, endpoint, test.addr)
+	}
+
+	for _, addr := range []string{"", ":80", "host:port", "host:70000"} {
+		_, err := tracing.EndpointFromAddr("svc", addr)
+		assert.Error(t, err, addr)
+	}
+}
+
+func TestEndpointFromNetAddr(t *testing.T) {
+	endpoint, err := tracing.EndpointFromNetAddr(&net.TCPAddr{IP: net.ParseIP("192.168.1.2"), Port: 80})
+	require.NoError(t, err)
+	assert.Equal(t, &tracing.Endpoint{IPv4: -1062731518, Port: 80}, endpoint)
+
+	endpoint, err = tracing.EndpointFromNetAddr(&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 53})
+	require.NoError(t, err)
+	assert.Equal(t, &tracing.Endpoint{IPv6: ipv6("2001:db8::1"), Port: 53}, endpoint)
+
+	endpoint, err = tracing.EndpointFromNetAddr(&net.UnixAddr{Name: "/tmp/sock", Net: "unix"})
+	require.NoError(t, err)
+	assert.Equal(t, &tracing.Endpoint{}, endpoint)
+
+	_, err = tracing.EndpointFromNetAddr(nil)
+	assert.Error(t, err)
+}
+
+func TestEndpointIP(t *testing.T) {
+	assert.Nil(t, (&tracing.Endpoint{}).IP())
+	assert.Equal(t, "192.168.1.2", (&tracing.Endpoint{IPv4: -1062731518}).IP().String())
+	assert.Equal(t, "::1", (&tracing.Endpoint{IPv4: 1, IPv6: ipv6("::1")}).IP().String())
+}
+
+func TestEndpointComparable(t *testing.T) {
+	a, err := tracing.EndpointFromAddr("svc", "[2001:db8::1]:80")
+	require.NoError(t, err)
+	b, err := tracing.EndpointFromAddr("svc", "[2001:db8::1]:80")
+	require.NoError(t, err)
+	assert.True(t, *a == *b)
+	assert.Len(t, map[tracing.Endpoint]bool{*a: true, *b: true}, 1)
+}
+
+func TestToZipkinEndpoint(t *testing.T) {
+	assert.Nil(t, tracing.ToZipkinEndpoint(nil))
+	assert.Equal(t, &tracing.ZipkinEndpoint{ServiceName: "svc", IPv4: "192.168.1.2", Port: 80},
+		tracing.ToZipkinEndpoint(&tracing.Endpoint{ServiceName: "svc", IPv4: -1062731518, Port: 80}))
+	assert.Equal(t, &tracing.ZipkinEndpoint{ServiceName: "svc", IPv4: "10.0.0.1", IPv6: "2001:db8::1"},
+		tracing.ToZipkinEndpoint(&tracing.Endpoint{ServiceName: "svc", IPv4: 10<<24 | 1, IPv6: ipv6("2001:db8::1")}))
+}
+
+func TestLocalEndpoint(t *testing.T) {
+	endpoint, err := tracing.LocalEndpoint("svc", 8080)
+	if err == tracing.NoLocalAddressError {
+		t.Skip("no network interfaces")
+	}
+	require.NoError(t, err)
+	assert.Equal(t, "svc", endpoint.ServiceName)
+	assert.EqualValues(t, 8080, endpoint.Port)
+	require.NotNil(t, endpoint.IP())
+	assert.False(t, endpoint.IP().IsLoopback())
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"runtime/trace"
+	"strconv"
+	"sync"
+)
+
+// ExecutionTraceEventCategory is the category of the runtime/trace log messages that mirror span events.
+const ExecutionTraceEventCategory = "span.event"
+
+type executionTracer struct {
+	tracer Tracer
+
+	mu sync.Mutex
+	// regions holds the regions open on each goroutine, innermost last, because the regions of a goroutine
+	// must end on that goroutine in the reverse order of their start.
+	regions map[uint64][]*executionRegion
+}
+
+type executionRegion struct {
+	region    *trace.Region
+	goroutine uint64
+	ended     bool
+}
+
+type executionSpan struct {
+	Span
+	tracer *executionTracer
+	// ctx holds the task of the span, or of the parent span for regions.
+	ctx    context.Context
+	task   *trace.Task
+	region *executionRegion
+}
+
+// taskContext is a context that carries the runtime/trace task of a span in addition to the values of the context.
+type taskContext struct {
+	context.Context
+	task context.Context
+}
+
+// NewExecutionTracer creates a tracer that delegates to the given tracer and mirrors the spans in the Go execution
+// trace, so that "go tool trace" shows the span names next to the scheduler events. Root spans, joined spans and
+// Async child spans become runtime/trace tasks, synchronous child spans become regions of the parent's task, and
+// events become log messages of the task. When a span is stored in a context via ContextWithSpan(), the context
+// carries its task, so that the regions and logs of the code running under the span belong to the task.
+//
+// Regions must end on the goroutine that started them, in the reverse order of their start. A synchronous child
+// ended before the children started after it is recorded as a span as usual, but its region only ends with the
+// last of them. The same goes for a synchronous child ended on another goroutine, unless the goroutine that
+// started it has no other open region, in which case the region is left open.
+func NewExecutionTracer(tracer Tracer) Tracer {
+	return &executionTracer{tracer: tracer, regions: make(map[uint64][]*executionRegion)}
+}
+
+// BeginTrace implements BeginTrace() of tracing.Tracer
+func (t *executionTracer) BeginTrace(spanName string, service *Endpoint, options *BeginOptions) Span {
+	return t.newTask(context.Background(), spanName, t.tracer.BeginTrace(spanName, service, options))
+}
+
+// JoinTrace implements JoinTrace() of tracing.Tracer
+func (t *executionTracer) JoinTrace(spanName string, service *Endpoint, spanID SpanID, options *BeginOptions) Span {
+	return t.newTask(context.Background(), spanName, t.tracer.JoinTrace(spanName, service, spanID, options))
+}
+
+func (t *executionTracer) wrappedTracer() Tracer {
+	return t.tracer
+}
+
+// GetStringPickler implements GetStringPickler() of tracing.Tracer
+func (t *executionTracer) GetStringPickler() StringPickler {
+	return t.tracer.GetStringPickler()
+}
+
+// Close implements Close() of tracing.Tracer
+func (t *executionTracer) Close() {
+	t.tracer.Close()
+}
+
+func (t *executionTracer) newTask(ctx context.Context, name string, span Span) *executionSpan {
+	ctx, task := trace.NewTask(ctx, name)
+	return &executionSpan{Span: span, tracer: t, ctx: ctx, task: task}
+}
+
+// startRegion starts a region on the current goroutine, or returns nil if the execution trace is not running.
+func (t *executionTracer) startRegion(ctx context.Context, name string) *executionRegion {
+	if !trace.IsEnabled() {
+		return nil
+	}
+	r := &executionRegion{region: trace.StartRegion(ctx, name), goroutine: goroutineID()}
+	t.mu.Lock()
+	t.regions[r.goroutine] = append(t.regions[r.goroutine], r)
+	t.mu.Unlock()
+	return r
+}
+
+// endRegion ends the region if it is the innermost region of the current goroutine, together with the regions
+// that enclose it and were ended before. Other regions are ended later, when they become the innermost region.
+func (t *executionTracer) endRegion(r *executionRegion) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r.ended = true
+	regions := t.regions[r.goroutine]
+	if goroutineID() == r.goroutine {
+		for len(regions) > 0 && regions[len(regions)-1].ended {
+			regions[len(regions)-1].region.End()
+			regions = regions[:len(regions)-1]
+		}
+	} else if allEnded(regions) {
+		// the goroutine may have exited, so its regions are left open rather than kept until it ends another one
+		regions = nil
+	}
+	if len(regions) == 0 {
+		delete(t.regions, r.goroutine)
+	} else {
+		t.regions[r.goroutine] = regions
+	}
+}
+
+func allEnded(regions []*executionRegion) bool {
+	for _, r := range regions {
+		if !r.ended {
+			return false
+		}
+	}
+	return true
+}
+
+// goroutineID returns the ID of the current goroutine, parsed from the header of its stack trace.
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	// the header is "goroutine <id> [<status>]:"
+	fields := bytes.Fields(buf[:n])
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseUint(string(fields[1]), 10, 64)
+	return id
+}
+
+// BeginChildSpan implements BeginChildSpan() of tracing.Span
+func (s *executionSpan) BeginChildSpan(name string, options *BeginOptions) Span {
+	child := s.Span.BeginChildSpan(name, options)
+	if options != nil && options.Async {
+		return s.tracer.newTask(s.ctx, name, child)
+	}
+	return &executionSpan{Span: child, tracer: s.tracer, ctx: s.ctx, region: s.tracer.startRegion(s.ctx, name)}
+}
+
+// End implements End() of tracing.Span
+func (s *executionSpan) End(options *EndOptions) {
+	s.Span.End(options)
+	if s.task != nil {
+		s.task.End()
+	} else if s.region != nil {
+		s.tracer.endRegion(s.region)
+	}
+}
+
+// AddEvent implements AddEvent() of tracing.Span
+func (s *executionSpan) AddEvent(name string, options *EventOptions) {
+	s.Span.AddEvent(name, options)
+	trace.Log(s.ctx, ExecutionTraceEventCategory, name)
+}
+
+func (s *executionSpan) attachToContext(ctx context.Context) context.Context {
+	return &taskContext{Context: ctx, task: s.ctx}
+}
+
+func (s *executionSpan) wrappedSpan() Span {
+	return s.Span
+}
+
+// Value implements Value() of context.Context. The task is looked up first.
+func (c *taskContext) Value(key interface{}) interface{} {
+	if v := c.task.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
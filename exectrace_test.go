@@ -0,0 +1,105 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"runtime/trace"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+func TestExecutionTracer(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("execution trace is already running")
+	}
+	mock := mocktracer.New()
+	tracer := tracing.NewExecutionTracer(mock)
+
+	var buf bytes.Buffer
+	require.NoError(t, trace.Start(&buf))
+	root := tracer.BeginTrace("root-task", endpoint, nil)
+	region := root.BeginChildSpan("sync-region", nil)
+	region.AddEvent("region-event", nil)
+	region.End(nil)
+	async := root.BeginChildSpan("async-task", &tracing.BeginOptions{Async: true})
+	done := make(chan struct{})
+	go func() {
+		async.AddEvent("async-event", nil)
+		async.End(nil)
+		close(done)
+	}()
+	<-done
+	root.End(nil)
+	trace.Stop()
+
+	for _, name := range []string{"root-task", "sync-region", "region-event", "async-task", "async-event",
+		tracing.ExecutionTraceEventCategory} {
+		assert.True(t, bytes.Contains(buf.Bytes(), []byte(name)), name)
+	}
+
+	spans := mock.FinishedSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "region-event", spans[1].Events()[0].Name)
+	assert.Equal(t, spans[0], spans[2].Parent)
+	assert.Zero(t, tracing.OpenExecutionRegions(tracer))
+}
+
+func TestExecutionTracerMisplacedEnds(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("execution trace is already running")
+	}
+	mock := mocktracer.New()
+	tracer := tracing.NewExecutionTracer(mock)
+
+	var buf bytes.Buffer
+	require.NoError(t, trace.Start(&buf))
+	defer trace.Stop()
+	root := tracer.BeginTrace("root", endpoint, nil)
+	ctx := tracing.ContextWithSpan(context.Background(), root)
+	trace.WithRegion(ctx, "user-region", func() {})
+
+	// ended before the child started after it
+	first := root.BeginChildSpan("first", nil)
+	second := root.BeginChildSpan("second", nil)
+	first.End(nil)
+	assert.Equal(t, 1, tracing.OpenExecutionRegions(tracer))
+	second.End(nil)
+	assert.Zero(t, tracing.OpenExecutionRegions(tracer))
+
+	// ended on another goroutine
+	child := root.BeginChildSpan("child", nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		child.End(nil)
+	}()
+	<-done
+	assert.Zero(t, tracing.OpenExecutionRegions(tracer), "the region of the child is left open")
+	root.End(nil)
+
+	assert.Len(t, mock.FinishedSpans(), 4)
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package exectracing propagates traces to child processes started with os/exec.
+//
+// The parent process stores the serialized span ID in an environment variable of the command, and the child
+// process joins the trace from that variable at startup:
+//
+//	// parent
+//	cmd := exec.CommandContext(ctx, "worker", "-batch", id)
+//	err := exectracing.Run(ctx, cmd, nil)
+//
+//	// child
+//	func main() {
+//		span, ctx, err := exectracing.StartFromEnv(context.Background(), "worker", endpoint, &exectracing.Options{Tracer: tracer})
+//		...
+//		defer span.End(nil)
+//	}
+//
+// Both processes must use tracers with compatible string picklers.
+package exectracing
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+// DefaultEnvVar is the environment variable that carries the serialized span ID to the child process.
+// It matches the X-Tracing header used by httptracing. The value is the string pickled span ID of the tracer,
+// not a W3C trace context, so the TRACEPARENT variable is only used if it is set explicitly in Options.EnvVar.
+const DefaultEnvVar = "X_TRACING"
+
+// ExitCodeKey is the attribute recording the exit code of the command on the span created by Run().
+const ExitCodeKey = "exec.exit_code"
+
+// Options contains optional settings that can be passed to the functions of this package.
+type Options struct {
+	// Tracer starts the spans that have no parent span in the context, and joins the trace in StartFromEnv().
+	// If nil, tracing.TracerFromContext() is used. Span IDs are serialized by the tracer stored in the context,
+	// which created the spans, or by Tracer if the context stores none.
+	Tracer tracing.Tracer
+
+	// EnvVar is the name of the environment variable carrying the serialized span ID. Defaults to DefaultEnvVar.
+	EnvVar string
+}
+
+func (o *Options) tracer(ctx context.Context) tracing.Tracer {
+	if o != nil && o.Tracer != nil {
+		return o.Tracer
+	}
+	return tracing.TracerFromContext(ctx)
+}
+
+// spanTracer returns the tracer serializing the IDs of the spans in the context.
+func (o *Options) spanTracer(ctx context.Context) tracing.Tracer {
+	if tracer := tracing.TracerFromContext(ctx); tracer != tracing.GlobalTracer() {
+		return tracer
+	}
+	return o.tracer(ctx)
+}
+
+func (o *Options) envVar() string {
+	if o != nil && o.EnvVar != "" {
+		return o.EnvVar
+	}
+	return DefaultEnvVar
+}
+
+// InjectEnv stores the ID of the current span in the context, serialized by the tracer stored in the context,
+// or by Options.Tracer if there is none, in the environment of the command.
+// If cmd.Env is nil, it is initialized from the environment of the current process, as exec.Cmd would do,
+// and an inherited value of the variable is replaced. The child process joins the span itself, so it is
+// usually a dedicated client span for the command, as created by Run(). If the context has no span,
+// tracing.NoCurrentSpanError is returned and the command is not changed.
+func InjectEnv(ctx context.Context, cmd *exec.Cmd, options *Options) error {
+	span, err := tracing.GetSpanFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	value := options.spanTracer(ctx).GetStringPickler().ToString(span.SpanID())
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	prefix := options.envVar() + "="
+	injected := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, prefix) {
+			injected = append(injected, kv)
+		}
+	}
+	cmd.Env = append(injected, prefix+value)
+	return nil
+}
+
+// Run runs the command under a client span that is a child of the current span in the context, or a root
+// span if there is none, see tracing.StartSpanFromContext(). The span is named after the base name of the
+// command path, its ID is injected into the environment of the command with InjectEnv(), and it is ended
+// with the error returned by cmd.Run(). The exit code of the process is recorded as the ExitCodeKey attribute.
+func Run(ctx context.Context, cmd *exec.Cmd, options *Options) error {
+	span, ctx := tracing.StartSpanFromContext(ctx, options.tracer(ctx), filepath.Base(cmd.Path),
+		&tracing.BeginOptions{Kind: tracing.SpanKindClient})
+	if err := InjectEnv(ctx, cmd, options); err != nil {
+		span.End(&tracing.EndOptions{Error: err})
+		return err
+	}
+	err := cmd.Run()
+	if cmd.ProcessState != nil {
+		span.AddAttribute(ExitCodeKey, int64(cmd.ProcessState.ExitCode()))
+	}
+	span.End(&tracing.EndOptions{Error: err})
+	return err
+}
+
+// StartFromEnv creates the top-level span of a child process, with the semantics of tracing.GetSpanFromHeader()
+// applied to the value of the environment variable: the process joins the span of the parent if the variable
+// is set, or starts a new trace otherwise. If service is nil, tracing.EndpointFromContext() is used. The span
+// is created as a server span and returned together with a context storing the span, the tracer and the
+// service endpoint. The variable is removed from the environment of the process,
+// so that processes started later without InjectEnv() do not join the same span. If the value cannot be parsed
+// as span ID, an error is returned and no span is created.
+func StartFromEnv(ctx context.Context, spanName string, service *tracing.Endpoint, options *Options) (tracing.Span, context.Context, error) {
+	tracer := options.tracer(ctx)
+	name := options.envVar()
+	header := os.Getenv(name)
+	os.Unsetenv(name)
+
+	if service == nil {
+		service = tracing.EndpointFromContext(ctx)
+	}
+	span, err := tracing.GetSpanFromHeader(header, tracer, spanName, service,
+		&tracing.BeginOptions{Kind: tracing.SpanKindServer})
+	if err != nil {
+		return nil, ctx, err
+	}
+	ctx = tracing.ContextWithEndpoint(tracing.ContextWithTracer(ctx, tracer), service)
+	return span, tracing.ContextWithSpan(ctx, span), nil
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package exectracing_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/exectracing"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+// childEnvVar makes the test binary act as the child process: it joins the trace, prints the serialized ID
+// of its span and the remaining value of the trace variable, and exits with the requested code.
+const childEnvVar = "EXECTRACING_TEST_CHILD"
+
+func TestMain(m *testing.M) {
+	if code := os.Getenv(childEnvVar); code != "" {
+		runChild(code)
+	}
+	os.Exit(m.Run())
+}
+
+func runChild(code string) {
+	tracer := mocktracer.New()
+	span, _, err := exectracing.StartFromEnv(context.Background(), "child",
+		&tracing.Endpoint{ServiceName: "child"}, &exectracing.Options{EnvVar: os.Getenv("EXECTRACING_TEST_VAR"), Tracer: tracer})
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(2)
+	}
+	span.End(nil)
+	fmt.Println(tracer.GetStringPickler().ToString(span.SpanID()))
+	fmt.Println(os.Getenv(exectracing.DefaultEnvVar))
+	exitCode, _ := strconv.Atoi(code)
+	os.Exit(exitCode)
+}
+
+func childCommand(exitCode int, envVar string) (*exec.Cmd, *strings.Builder) {
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(),
+		childEnvVar+"="+strconv.Itoa(exitCode),
+		"EXECTRACING_TEST_VAR="+envVar)
+	out := &strings.Builder{}
+	cmd.Stdout = out
+	return cmd, out
+}
+
+func TestRun(t *testing.T) {
+	tracer := mocktracer.New()
+	parent, ctx := tracing.StartSpanFromContext(context.Background(), tracer, "parent", nil)
+	defer parent.End(nil)
+
+	cmd, out := childCommand(0, "")
+	cmd.Env = append(cmd.Env, exectracing.DefaultEnvVar+"=stale")
+	require.NoError(t, exectracing.Run(ctx, cmd, nil))
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, filepath.Base(os.Args[0]), span.Name)
+	assert.Equal(t, tracing.SpanKindClient, span.Kind)
+	assert.Equal(t, parent, span.Parent)
+	exitCode, _ := span.Attribute(exectracing.ExitCodeKey)
+	assert.Equal(t, int64(0), exitCode)
+	assert.NoError(t, span.EndOptions().Error)
+
+	lines := strings.Split(out.String(), "\n")
+	require.Len(t, lines, 3, out.String())
+	assert.Equal(t, tracer.GetStringPickler().ToString(span.SpanID()), lines[0], "the child joins the span")
+	assert.Empty(t, lines[1], "the variable is removed in the child")
+	assert.Equal(t, 1, strings.Count(strings.Join(cmd.Env, "\n"), exectracing.DefaultEnvVar+"="),
+		"the inherited value is replaced")
+}
+
+func TestRunFailure(t *testing.T) {
+	tracer := mocktracer.New()
+	cmd, out := childCommand(3, "CUSTOM_TRACE")
+	err := exectracing.Run(context.Background(), cmd, &exectracing.Options{Tracer: tracer, EnvVar: "CUSTOM_TRACE"})
+	require.Error(t, err)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Nil(t, spans[0].Parent)
+	exitCode, _ := spans[0].Attribute(exectracing.ExitCodeKey)
+	assert.Equal(t, int64(3), exitCode)
+	assert.Equal(t, err, spans[0].EndOptions().Error)
+	assert.Equal(t, tracer.GetStringPickler().ToString(spans[0].SpanID())+"\n\n", out.String())
+}
+
+func TestInjectEnvWithoutSpan(t *testing.T) {
+	cmd := exec.Command("true")
+	err := exectracing.InjectEnv(context.Background(), cmd, nil)
+	assert.Equal(t, tracing.NoCurrentSpanError, err)
+	assert.Nil(t, cmd.Env)
+}
+
+func TestInjectEnvWithOptionsTracer(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.BeginTrace("parent", nil, nil)
+	ctx := tracing.ContextWithSpan(context.Background(), span)
+
+	cmd := exec.Command("true")
+	cmd.Env = []string{}
+	require.NoError(t, exectracing.InjectEnv(ctx, cmd, &exectracing.Options{Tracer: tracer}))
+	assert.Equal(t, []string{exectracing.DefaultEnvVar + "=" + tracer.GetStringPickler().ToString(span.SpanID())}, cmd.Env)
+}
+
+func TestStartFromEnv(t *testing.T) {
+	tracer := mocktracer.New()
+	options := &exectracing.Options{Tracer: tracer}
+
+	t.Setenv(exectracing.DefaultEnvVar, "")
+	span, ctx, err := exectracing.StartFromEnv(context.Background(), "root", nil, options)
+	require.NoError(t, err)
+	assert.Equal(t, tracing.SpanKindServer, tracer.Spans()[0].Kind)
+	current, err := tracing.GetSpanFromContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, span, current)
+	assert.Equal(t, tracing.Tracer(tracer), tracing.TracerFromContext(ctx))
+
+	t.Setenv(exectracing.DefaultEnvVar, "malformed")
+	span, _, err = exectracing.StartFromEnv(context.Background(), "bad", nil, options)
+	assert.Error(t, err)
+	assert.Nil(t, span)
+}
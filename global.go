@@ -0,0 +1,98 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import "sync/atomic"
+
+type registeredTracer struct {
+	tracer Tracer
+}
+
+type globalTracer struct{}
+
+var (
+	registered       atomic.Pointer[registeredTracer]
+	forwardingTracer globalTracer
+)
+
+func init() {
+	registered.Store(&registeredTracer{tracer: NewNoopTracer()})
+}
+
+// SetGlobalTracer registers the tracer that GlobalTracer() forwards to. It can be called at any time and from
+// any goroutine, but is meant to be called once, when the program configures tracing. Passing nil restores
+// the noop tracer.
+func SetGlobalTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = NewNoopTracer()
+	}
+	registered.Store(&registeredTracer{tracer: tracer})
+}
+
+// GlobalTracer returns a tracer that forwards every call to the tracer currently registered with
+// SetGlobalTracer(), or to a noop tracer if none was registered. Because the calls are forwarded, libraries
+// may capture the global tracer in package variables before the program configures tracing.
+// The global tracer implements ZipkinCompatibleTracer by forwarding to the registered tracer, or to the
+// tracer it decorates. Other optional interfaces of the registered tracer are not visible through the global
+// tracer; use RegisteredTracer() to access them.
+func GlobalTracer() Tracer {
+	return &forwardingTracer
+}
+
+// RegisteredTracer returns the tracer currently registered with SetGlobalTracer().
+func RegisteredTracer() Tracer {
+	return registered.Load().tracer
+}
+
+// BeginTrace implements BeginTrace() of tracing.Tracer
+func (t *globalTracer) BeginTrace(spanName string, service *Endpoint, options *BeginOptions) Span {
+	return RegisteredTracer().BeginTrace(spanName, service, options)
+}
+
+// JoinTrace implements JoinTrace() of tracing.Tracer
+func (t *globalTracer) JoinTrace(spanName string, service *Endpoint, spanID SpanID, options *BeginOptions) Span {
+	return RegisteredTracer().JoinTrace(spanName, service, spanID, options)
+}
+
+// CreateSpanID implements CreateSpanID() of tracing.ZipkinCompatibleTracer. It returns nil if neither the
+// registered tracer nor the tracers it decorates are Zipkin-compatible.
+func (t *globalTracer) CreateSpanID(traceID, spanID, parentID int64, flags byte) ZipkinSpanID {
+	for tracer := RegisteredTracer(); tracer != nil; tracer = unwrapTracer(tracer) {
+		if zt, ok := tracer.(ZipkinCompatibleTracer); ok {
+			return zt.CreateSpanID(traceID, spanID, parentID, flags)
+		}
+	}
+	return nil
+}
+
+func (t *globalTracer) wrappedTracer() Tracer {
+	return RegisteredTracer()
+}
+
+// GetStringPickler implements GetStringPickler() of tracing.Tracer
+func (t *globalTracer) GetStringPickler() StringPickler {
+	return RegisteredTracer().GetStringPickler()
+}
+
+// Close implements Close() of tracing.Tracer
+func (t *globalTracer) Close() {
+	RegisteredTracer().Close()
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+// captured before any tracer is registered, like a library package variable would be
+var capturedTracer = tracing.GlobalTracer()
+
+func TestGlobalTracer(t *testing.T) {
+	defer tracing.SetGlobalTracer(nil)
+
+	assert.Equal(t, tracing.NewNoopTracer(), tracing.RegisteredTracer())
+	capturedTracer.BeginTrace("noop", endpoint, nil).End(nil)
+
+	mock := mocktracer.New()
+	tracing.SetGlobalTracer(mock)
+	assert.Equal(t, mock, tracing.RegisteredTracer())
+
+	capturedTracer.BeginTrace("root", endpoint, nil).End(nil)
+	capturedTracer.JoinTrace("server", endpoint, mock.CreateSpanID(1, 2, 0, 1), nil).End(nil)
+	assert.Equal(t, mock.GetStringPickler(), capturedTracer.GetStringPickler())
+	assert.Len(t, mock.FinishedSpans(), 2)
+
+	capturedTracer.Close()
+	assert.True(t, mock.Closed())
+
+	tracing.SetGlobalTracer(nil)
+	assert.Equal(t, tracing.NewNoopTracer(), tracing.RegisteredTracer())
+	capturedTracer.BeginTrace("noop", endpoint, nil).End(nil)
+	assert.Len(t, mock.Spans(), 2)
+}
+
+func TestGlobalTracerCreateSpanID(t *testing.T) {
+	defer tracing.SetGlobalTracer(nil)
+
+	zipkinTracer, ok := capturedTracer.(tracing.ZipkinCompatibleTracer)
+	require.True(t, ok)
+	assert.Equal(t, "tracing-disabled", zipkinTracer.CreateSpanID(1, 2, 0, 1).String())
+
+	mock := mocktracer.New()
+	tracing.SetGlobalTracer(tracing.NewDebugTracer(mock, nil))
+	assert.Equal(t, mock.CreateSpanID(1, 2, 0, 1), zipkinTracer.CreateSpanID(1, 2, 0, 1),
+		"the decorated tracer creates the ID")
+
+	tracing.SetGlobalTracer(struct{ tracing.Tracer }{mock})
+	assert.Nil(t, zipkinTracer.CreateSpanID(1, 2, 0, 1))
+}
+
+func TestGlobalTracerConcurrentReplacement(t *testing.T) {
+	defer tracing.SetGlobalTracer(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			tracing.SetGlobalTracer(mocktracer.New())
+		}()
+		go func() {
+			defer wg.Done()
+			tracing.GlobalTracer().BeginTrace("span", endpoint, nil).End(nil)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"sync"
+)
+
+// Go runs fn in a new goroutine as an Async child span of the current span in the context, or as a root span
+// if there is none, see StartSpan(). The span is stored in the context passed to fn and ended when fn returns.
+// If fn panics, the span is ended with the panic recorded in EndOptions.Error and the panic is re-raised.
+func Go(ctx context.Context, spanName string, fn func(ctx context.Context)) {
+	span, spanCtx := StartSpan(ctx, spanName, &BeginOptions{Async: true})
+	go runInSpan(spanCtx, span, func(ctx context.Context) error {
+		fn(ctx)
+		return nil
+	})
+}
+
+// runInSpan calls fn with the context of the span and ends the span with the returned error or panic.
+// The goroutine carries the runtime/pprof labels of the context while fn runs, see NewProfilerLabelTracer().
+func runInSpan(ctx context.Context, span Span, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			span.End(&EndOptions{Error: fmt.Errorf("panic: %v", r)})
+			panic(r)
+		}
+	}()
+	pprof.Do(ctx, pprof.Labels(), func(ctx context.Context) {
+		err = fn(ctx)
+	})
+	span.End(&EndOptions{Error: err})
+	return err
+}
+
+// Group runs a collection of functions in goroutines as Async child spans, similar to errgroup.Group.
+// The first function to return an error cancels the group's context, and its error is returned by Wait().
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+// WithGroup creates a Group whose spans are children of the current span in the context. The returned
+// context is derived from ctx and is canceled when a function in the group fails or when Wait() returns.
+func WithGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// Go runs fn in a new goroutine under an Async span with the given name. The span is stored in the context
+// passed to fn, and ended with the error returned by fn. Panics are recorded and re-raised as in tracing.Go().
+func (g *Group) Go(spanName string, fn func(ctx context.Context) error) {
+	span, spanCtx := StartSpan(g.ctx, spanName, &BeginOptions{Async: true})
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := runInSpan(spanCtx, span, fn); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+}
+
+// Wait blocks until all functions started with Go() have returned, then returns the first error, if any.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+	return g.err
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+func TestGo(t *testing.T) {
+	tracer := mocktracer.New()
+	parent, ctx := tracing.StartSpanFromContext(context.Background(), tracer, "parent", nil)
+
+	done := make(chan tracing.Span)
+	tracing.Go(ctx, "worker", func(ctx context.Context) {
+		span, err := tracing.GetSpanFromContext(ctx)
+		assert.NoError(t, err)
+		done <- span
+	})
+	span := <-done
+	assert.NotEqual(t, parent, span)
+	parent.End(nil)
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 2)
+	worker := spans[1]
+	assert.Equal(t, "worker", worker.Name)
+	assert.Equal(t, spans[0], worker.Parent)
+	assert.True(t, worker.Options.Async)
+
+	require.Eventually(t, worker.Ended, time.Second, time.Millisecond)
+	assert.NoError(t, worker.EndOptions().Error)
+	assert.Nil(t, worker.EndOptions().Duration)
+}
+
+func TestRunInSpanPanic(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.BeginTrace("worker", endpoint, nil)
+
+	var recovered interface{}
+	func() {
+		defer func() { recovered = recover() }()
+		tracing.RunInSpan(context.Background(), span, func(context.Context) error { panic("boom") })
+	}()
+	assert.Equal(t, "boom", recovered)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.EqualError(t, spans[0].EndOptions().Error, "panic: boom")
+}
+
+func TestGroup(t *testing.T) {
+	tracer := mocktracer.New()
+	_, ctx := tracing.StartSpanFromContext(context.Background(), tracer, "parent", nil)
+
+	g, groupCtx := tracing.WithGroup(ctx)
+	failure := errors.New("failure")
+	g.Go("ok", func(ctx context.Context) error {
+		_, err := tracing.GetSpanFromContext(ctx)
+		return err
+	})
+	g.Go("fails", func(ctx context.Context) error {
+		return failure
+	})
+	assert.Equal(t, failure, g.Wait())
+	assert.Error(t, groupCtx.Err())
+	assert.Equal(t, failure, context.Cause(groupCtx))
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 2)
+	for _, s := range spans {
+		assert.True(t, s.Options.Async)
+		assert.Equal(t, tracer.Spans()[0], s.Parent)
+		if s.Name == "fails" {
+			assert.Equal(t, failure, s.EndOptions().Error)
+		} else {
+			assert.NoError(t, s.EndOptions().Error)
+		}
+	}
+}
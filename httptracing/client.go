@@ -0,0 +1,175 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httptracing
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+// ClientOptions contains optional settings that can be passed to NewTransport().
+type ClientOptions struct {
+	// Tracer starts the spans of requests whose context has no span. If nil, tracing.TracerFromContext() is used.
+	// Span IDs are serialized by the tracer stored in the context with the parent span, or by Tracer if the
+	// context stores none, see tracing.StartSpanFromContext().
+	Tracer tracing.Tracer
+
+	// Propagator injects the span ID into the request. If nil, HeaderPropagator(DefaultHeader) is used.
+	Propagator Propagator
+
+	// SpanName returns the name of the span for the request. The domain of names must be limited.
+	// If nil, SpanNameFromMethod is used.
+	SpanName func(r *http.Request) string
+
+	// ClientTrace enables recording of connection-level events on the client spans, see WithClientTrace().
+	ClientTrace bool
+}
+
+type transport struct {
+	base    http.RoundTripper
+	options ClientOptions
+}
+
+// NewTransport returns an http.RoundTripper that traces every request made through the base round tripper,
+// or through http.DefaultTransport if base is nil. For each request it starts a client span as a child of
+// the span in the request context, sets the peer from the URL host and injects the span ID into a copy of
+// the request. The span records the method, the status code and the size of the response body, and it is
+// ended when the response body is fully read or closed, so that streaming responses are timed correctly.
+// Transport errors and responses with 5xx status codes are recorded as errors.
+func NewTransport(base http.RoundTripper, options *ClientOptions) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &transport{base: base}
+	if options != nil {
+		t.options = *options
+	}
+	if t.options.Propagator == nil {
+		t.options.Propagator = HeaderPropagator(DefaultHeader)
+	}
+	if t.options.SpanName == nil {
+		t.options.SpanName = SpanNameFromMethod
+	}
+	return t
+}
+
+// RoundTrip implements RoundTrip() of http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tracer := t.options.Tracer
+	if tracer == nil {
+		tracer = tracing.TracerFromContext(req.Context())
+	}
+	options := &tracing.BeginOptions{Kind: tracing.SpanKindClient, Peer: peerFromURLHost(req)}
+	span, ctx := tracing.StartSpanFromContext(req.Context(), tracer, t.options.SpanName(req), options)
+	span.AddAttribute(MethodKey, req.Method)
+
+	if t.options.ClientTrace {
+		ctx = WithClientTrace(ctx)
+	}
+	req = req.Clone(ctx)
+	// the span ID is serialized by the tracer of the parent span, if any
+	t.options.Propagator.Inject(tracing.TracerFromContext(ctx).GetStringPickler().ToString(span.SpanID()), req.Header)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.End(&tracing.EndOptions{Error: err})
+		return nil, err
+	}
+	span.AddAttribute(StatusCodeKey, int64(resp.StatusCode))
+
+	body := &tracedBody{ReadCloser: resp.Body, span: span}
+	if resp.StatusCode >= 500 {
+		body.err = fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+	if rwc, ok := resp.Body.(io.ReadWriteCloser); ok {
+		// keep the body writable for 101 Switching Protocols responses
+		resp.Body = &tracedReadWriteBody{tracedBody: body, writer: rwc}
+	} else {
+		resp.Body = body
+	}
+	return resp, nil
+}
+
+func peerFromURLHost(req *http.Request) *tracing.Endpoint {
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	host := req.URL.Hostname()
+	peer, err := tracing.EndpointFromAddr(host, net.JoinHostPort(host, port))
+	if err != nil {
+		return nil
+	}
+	return peer
+}
+
+// tracedBody ends the span when the response body is read to the end or closed.
+type tracedBody struct {
+	io.ReadCloser
+	span tracing.Span
+	once sync.Once
+	size int64
+	err  error
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if err == io.EOF {
+		b.end(nil)
+	} else if err != nil {
+		b.end(err)
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.end(nil)
+	return err
+}
+
+func (b *tracedBody) end(err error) {
+	b.once.Do(func() {
+		if b.err == nil {
+			b.err = err
+		}
+		b.span.AddAttribute(ResponseSizeKey, b.size)
+		b.span.End(&tracing.EndOptions{Error: b.err})
+	})
+}
+
+type tracedReadWriteBody struct {
+	*tracedBody
+	writer io.Writer
+}
+
+func (b *tracedReadWriteBody) Write(p []byte) (int, error) {
+	return b.writer.Write(p)
+}
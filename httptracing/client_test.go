@@ -0,0 +1,159 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httptracing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/httptracing"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+func TestTransport(t *testing.T) {
+	tracer := mocktracer.New()
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(httptracing.DefaultHeader)
+		io.WriteString(w, "response body")
+	}))
+	defer server.Close()
+
+	parent, ctx := tracing.StartSpanFromContext(context.Background(), tracer, "parent", nil)
+	// the tracer of the parent span serializes the ID, not the one for new traces
+	client := &http.Client{Transport: httptracing.NewTransport(nil, &httptracing.ClientOptions{Tracer: tracing.NewNoopTracer()})}
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/path", nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Get(httptracing.DefaultHeader), "the original request must not be modified")
+
+	// the span is not ended until the body is consumed
+	require.Len(t, tracer.Spans(), 2)
+	span := tracer.Spans()[1]
+	assert.False(t, span.Ended())
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "response body", string(body))
+	assert.True(t, span.Ended())
+	resp.Body.Close()
+
+	assert.Equal(t, tracer.GetStringPickler().ToString(span.SpanID()), received)
+	assert.Equal(t, tracer.Spans()[0], span.Parent)
+	assert.Equal(t, "HTTP GET", span.Name)
+	assert.Equal(t, tracing.SpanKindClient, span.Kind)
+	require.NotNil(t, span.Options.Peer)
+	assert.Equal(t, "127.0.0.1", span.Options.Peer.ServiceName)
+	assert.EqualValues(t, 127<<24|1, span.Options.Peer.IPv4)
+	assert.Equal(t, server.Listener.Addr().String(), fmt.Sprintf("127.0.0.1:%d", span.Options.Peer.Port))
+	status, _ := span.Attribute(httptracing.StatusCodeKey)
+	assert.Equal(t, int64(200), status)
+	size, _ := span.Attribute(httptracing.ResponseSizeKey)
+	assert.Equal(t, int64(13), size)
+	assert.NoError(t, span.EndOptions().Error)
+	parent.End(nil)
+}
+
+func TestTransportParentWithoutTracer(t *testing.T) {
+	tracer := mocktracer.New()
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(httptracing.DefaultHeader)
+	}))
+	defer server.Close()
+
+	// the context stores the parent span, but not its tracer
+	ctx := tracing.ContextWithSpan(context.Background(), tracer.BeginTrace("parent", nil, nil))
+	client := &http.Client{Transport: httptracing.NewTransport(nil, &httptracing.ClientOptions{Tracer: tracer})}
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Len(t, tracer.Spans(), 2)
+	assert.Equal(t, tracer.GetStringPickler().ToString(tracer.Spans()[1].SpanID()), received)
+}
+
+func TestTransportStreaming(t *testing.T) {
+	tracer := mocktracer.New()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.(http.Flusher).Flush()
+		<-release
+		io.WriteString(w, "late")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: httptracing.NewTransport(nil, &httptracing.ClientOptions{
+		Tracer:     tracer,
+		Propagator: httptracing.HeaderPropagator("Trace-Me"),
+		SpanName:   func(*http.Request) string { return "download" },
+	})}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+	require.Len(t, tracer.Spans(), 1)
+	span := tracer.Spans()[0]
+	assert.False(t, span.Ended(), "headers arrived, but the body is still streaming")
+	close(release)
+	resp.Body.Close()
+
+	assert.True(t, span.Ended())
+	assert.Equal(t, "download", span.Name)
+	assert.Nil(t, span.Parent)
+	assert.EqualError(t, span.EndOptions().Error, "HTTP status 502")
+}
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestTransportError(t *testing.T) {
+	tracer := mocktracer.New()
+	ctx := tracing.ContextWithTracer(context.Background(), tracer)
+	client := &http.Client{Transport: httptracing.NewTransport(failingTransport{}, nil)}
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://example.com/upload", strings.NewReader("x"))
+	require.NoError(t, err)
+	_, err = client.Do(req)
+	assert.Error(t, err)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.EqualError(t, spans[0].EndOptions().Error, "connection refused")
+	assert.Equal(t, "example.com", spans[0].Options.Peer.ServiceName)
+	assert.EqualValues(t, 443, spans[0].Options.Peer.Port)
+	assert.Zero(t, spans[0].Options.Peer.IPv4)
+}
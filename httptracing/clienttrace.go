@@ -0,0 +1,92 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httptracing
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+// Names of the events recorded by the client trace installed with WithClientTrace().
+const (
+	DNSStartEvent          = "dns.start"
+	DNSDoneEvent           = "dns.done"
+	ConnectStartEvent      = "connect.start"
+	ConnectDoneEvent       = "connect.done"
+	TLSHandshakeStartEvent = "tls.handshake.start"
+	TLSHandshakeDoneEvent  = "tls.handshake.done"
+	GotConnEvent           = "got_conn"
+	WroteRequestEvent      = "wrote_request"
+	FirstResponseByteEvent = "first_response_byte"
+)
+
+// ConnReusedKey is the attribute that records whether the request was sent over a reused connection.
+const ConnReusedKey = "http.conn_reused"
+
+// Attributes that record the errors of the failed steps of an outgoing request traced with WithClientTrace().
+// The done event of the step is recorded as well.
+const (
+	DNSErrorKey          = "http.dns_error"
+	ConnectErrorKey      = "http.connect_error"
+	TLSHandshakeErrorKey = "http.tls_handshake_error"
+)
+
+// WithClientTrace returns a child context with an httptrace.ClientTrace that records the progress of an outgoing
+// request as timestamped events on the current span in the context: DNS lookup, connection establishment,
+// TLS handshake, obtaining the connection, writing the request and receiving the first response byte.
+// Failures of the DNS lookup, the connection and the TLS handshake are recorded as attributes of the span.
+// If the context has no span, it is returned unchanged. Client traces already in the context keep working.
+func WithClientTrace(ctx context.Context) context.Context {
+	span, err := tracing.GetSpanFromContext(ctx)
+	if err != nil {
+		return ctx
+	}
+	event := func(name string) {
+		ts := tracing.Timestamp(time.Now())
+		span.AddEvent(name, &tracing.EventOptions{TimeOption: tracing.TimeOption{Timestamp: &ts}})
+	}
+	done := func(name, key string, err error) {
+		event(name)
+		if err != nil {
+			span.AddAttribute(key, err.Error())
+		}
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { event(DNSStartEvent) },
+		DNSDone:           func(info httptrace.DNSDoneInfo) { done(DNSDoneEvent, DNSErrorKey, info.Err) },
+		ConnectStart:      func(string, string) { event(ConnectStartEvent) },
+		ConnectDone:       func(_, _ string, err error) { done(ConnectDoneEvent, ConnectErrorKey, err) },
+		TLSHandshakeStart: func() { event(TLSHandshakeStartEvent) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			done(TLSHandshakeDoneEvent, TLSHandshakeErrorKey, err)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			span.AddAttribute(ConnReusedKey, info.Reused)
+			event(GotConnEvent)
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { event(WroteRequestEvent) },
+		GotFirstResponseByte: func() { event(FirstResponseByteEvent) },
+	})
+}
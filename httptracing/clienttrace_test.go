@@ -0,0 +1,121 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httptracing_test
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go/httptracing"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+func TestClientTrace(t *testing.T) {
+	tracer := mocktracer.New()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	transport := httptracing.NewTransport(server.Client().Transport, &httptracing.ClientOptions{
+		Tracer:      tracer,
+		ClientTrace: true,
+	})
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 2)
+
+	var names []string
+	for _, event := range spans[0].Events() {
+		names = append(names, event.Name)
+		assert.NotNil(t, event.Options.Timestamp)
+	}
+	assert.Equal(t, []string{
+		httptracing.ConnectStartEvent,
+		httptracing.ConnectDoneEvent,
+		httptracing.TLSHandshakeStartEvent,
+		httptracing.TLSHandshakeDoneEvent,
+		httptracing.GotConnEvent,
+		httptracing.WroteRequestEvent,
+		httptracing.FirstResponseByteEvent,
+	}, names, "the server address is an IP, so there is no DNS lookup")
+	reused, _ := spans[0].Attribute(httptracing.ConnReusedKey)
+	assert.Equal(t, false, reused)
+
+	// the second request reuses the connection
+	names = nil
+	for _, event := range spans[1].Events() {
+		names = append(names, event.Name)
+	}
+	assert.Equal(t, []string{
+		httptracing.GotConnEvent,
+		httptracing.WroteRequestEvent,
+		httptracing.FirstResponseByteEvent,
+	}, names)
+	reused, _ = spans[1].Attribute(httptracing.ConnReusedKey)
+	assert.Equal(t, true, reused)
+}
+
+func TestClientTraceErrors(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name string
+		url  string
+		key  string
+	}{
+		{"dns", "http://nonexistent.invalid/", httptracing.DNSErrorKey},
+		{"connect", "http://" + closedAddr + "/", httptracing.ConnectErrorKey},
+		{"tls", tlsServer.URL, httptracing.TLSHandshakeErrorKey},
+	}
+	for _, test := range tests {
+		tracer := mocktracer.New()
+		transport := httptracing.NewTransport(&http.Transport{}, &httptracing.ClientOptions{
+			Tracer:      tracer,
+			ClientTrace: true,
+		})
+		_, err := (&http.Client{Transport: transport}).Get(test.url)
+		require.Error(t, err, test.name)
+
+		spans := tracer.FinishedSpans()
+		require.Len(t, spans, 1, test.name)
+		message, ok := spans[0].Attribute(test.key)
+		assert.True(t, ok, test.name)
+		assert.NotEmpty(t, message, test.name)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package httptracing instruments net/http servers and clients.
+package httptracing
+
+import (
+	"net/http"
+)
+
+// DefaultHeader is the HTTP header that carries the serialized span ID between services.
+const DefaultHeader = "X-Tracing"
+
+// Propagator transfers the serialized span ID, as produced by the tracer's StringPickler, in HTTP headers.
+type Propagator interface {
+	// Inject stores the serialized span ID in the headers of an outgoing request.
+	Inject(value string, header http.Header)
+
+	// Extract returns the serialized span ID from the headers of an incoming request,
+	// or an empty string if there is none.
+	Extract(header http.Header) string
+}
+
+type headerPropagator string
+
+// HeaderPropagator returns a Propagator that stores the serialized span ID in a single header with the given name.
+func HeaderPropagator(name string) Propagator {
+	return headerPropagator(name)
+}
+
+// Inject implements Inject() of httptracing.Propagator
+func (p headerPropagator) Inject(value string, header http.Header) {
+	header.Set(string(p), value)
+}
+
+// Extract implements Extract() of httptracing.Propagator
+func (p headerPropagator) Extract(header http.Header) string {
+	return header.Get(string(p))
+}
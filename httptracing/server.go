@@ -0,0 +1,257 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httptracing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+// Names of the attributes recorded on HTTP spans.
+const (
+	MethodKey       = "http.method"
+	RouteKey        = "http.route"
+	StatusCodeKey   = "http.status_code"
+	ResponseSizeKey = "http.response_size"
+)
+
+// ServerOptions contains optional settings that can be passed to Middleware().
+type ServerOptions struct {
+	// Tracer creates the server spans. If nil, the tracer registered with tracing.SetGlobalTracer() is used.
+	Tracer tracing.Tracer
+
+	// Endpoint describes the service handling the requests. It is stored in the request context, see
+	// tracing.ContextWithEndpoint(). If nil, tracing.EndpointFromContext() of the request is used.
+	Endpoint *tracing.Endpoint
+
+	// Propagator extracts the span ID from the request. If nil, HeaderPropagator(DefaultHeader) is used.
+	Propagator Propagator
+
+	// SpanName returns the name of the span for the request. The domain of names must be limited, so the
+	// function must not include URL paths with IDs or other unbounded values. If nil, SpanNameFromMethod is used.
+	SpanName func(r *http.Request) string
+}
+
+// SpanNameFromMethod names the span after the HTTP method, e.g. "HTTP GET".
+func SpanNameFromMethod(r *http.Request) string {
+	return "HTTP " + r.Method
+}
+
+// Middleware returns a handler that traces every request before passing it to the next handler.
+// It joins the trace of the caller if the request carries a span ID, or starts a new trace otherwise,
+// and stores the span, the tracer and the endpoint in the request context. The span records the method, the route
+// matched by http.ServeMux, the status code and the size of the response. Responses with 5xx status
+// codes and panics in the handler are recorded as errors; panics are re-raised after the span ends.
+func Middleware(next http.Handler, options *ServerOptions) http.Handler {
+	opts := ServerOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Tracer == nil {
+		opts.Tracer = tracing.GlobalTracer()
+	}
+	if opts.Propagator == nil {
+		opts.Propagator = HeaderPropagator(DefaultHeader)
+	}
+	if opts.SpanName == nil {
+		opts.SpanName = SpanNameFromMethod
+	}
+	return &middleware{next: next, options: opts}
+}
+
+type middleware struct {
+	next    http.Handler
+	options ServerOptions
+}
+
+func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	spanName := m.options.SpanName(r)
+	endpoint := m.options.Endpoint
+	if endpoint == nil {
+		endpoint = tracing.EndpointFromContext(r.Context())
+	}
+	beginOptions := &tracing.BeginOptions{Kind: tracing.SpanKindServer, Peer: peerFromRemoteAddr(r.RemoteAddr)}
+	header := m.options.Propagator.Extract(r.Header)
+	span, err := tracing.GetSpanFromHeader(header, m.options.Tracer, spanName, endpoint, beginOptions)
+	if err != nil {
+		// the header could not be parsed, but the request is still traced
+		span = m.options.Tracer.BeginTrace(spanName, endpoint, beginOptions)
+	}
+	span.AddAttribute(MethodKey, r.Method)
+
+	ctx := tracing.ContextWithEndpoint(tracing.ContextWithTracer(r.Context(), m.options.Tracer), endpoint)
+	ctx = tracing.ContextWithSpan(ctx, span)
+	r = r.WithContext(ctx)
+	rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+	defer func() {
+		if route := r.Pattern; route != "" {
+			span.AddAttribute(RouteKey, route)
+		}
+		span.AddAttribute(StatusCodeKey, int64(rw.status))
+		span.AddAttribute(ResponseSizeKey, rw.size)
+
+		if p := recover(); p != nil {
+			span.End(&tracing.EndOptions{Error: fmt.Errorf("panic: %v", p)})
+			panic(p)
+		}
+		var endErr error
+		if rw.status >= 500 {
+			endErr = fmt.Errorf("HTTP status %d", rw.status)
+		}
+		span.End(&tracing.EndOptions{Error: endErr})
+	}()
+	m.next.ServeHTTP(rw.wrap(), r)
+}
+
+// peerFromRemoteAddr converts http.Request.RemoteAddr to the peer endpoint. The service name of the peer is unknown.
+func peerFromRemoteAddr(addr string) *tracing.Endpoint {
+	peer, err := tracing.EndpointFromAddr("", addr)
+	if err != nil {
+		return nil
+	}
+	return peer
+}
+
+// responseWriter captures the status code and the size of the response.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// informational 1xx responses may precede the final status
+	if !w.wroteHeader && status >= 200 {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// wrap returns the writer passed to the handler. It implements http.Flusher, http.Hijacker and io.ReaderFrom
+// only if the underlying writer does, so that handlers probing for them see the capabilities of the connection.
+func (w *responseWriter) wrap() http.ResponseWriter {
+	_, isFlusher := w.ResponseWriter.(http.Flusher)
+	_, isHijacker := w.ResponseWriter.(http.Hijacker)
+	_, isReaderFrom := w.ResponseWriter.(io.ReaderFrom)
+	f, h, rf := flusher{w}, hijacker{w}, readerFrom{w}
+	switch {
+	case isFlusher && isHijacker && isReaderFrom:
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{w, f, h, rf}
+	case isFlusher && isHijacker:
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+		}{w, f, h}
+	case isFlusher && isReaderFrom:
+		return struct {
+			*responseWriter
+			http.Flusher
+			io.ReaderFrom
+		}{w, f, rf}
+	case isHijacker && isReaderFrom:
+		return struct {
+			*responseWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{w, h, rf}
+	case isFlusher:
+		return struct {
+			*responseWriter
+			http.Flusher
+		}{w, f}
+	case isHijacker:
+		return struct {
+			*responseWriter
+			http.Hijacker
+		}{w, h}
+	case isReaderFrom:
+		return struct {
+			*responseWriter
+			io.ReaderFrom
+		}{w, rf}
+	default:
+		return w
+	}
+}
+
+type flusher struct {
+	w *responseWriter
+}
+
+type hijacker struct {
+	w *responseWriter
+}
+
+type readerFrom struct {
+	w *responseWriter
+}
+
+// Flush implements Flush() of http.Flusher
+func (f flusher) Flush() {
+	f.w.wroteHeader = true
+	f.w.ResponseWriter.(http.Flusher).Flush()
+}
+
+// Hijack implements Hijack() of http.Hijacker, e.g. for WebSocket handshakes. The status of a hijacked
+// connection is recorded as 101 Switching Protocols.
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := h.w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && !h.w.wroteHeader {
+		h.w.status = http.StatusSwitchingProtocols
+		h.w.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// ReadFrom implements ReadFrom() of io.ReaderFrom, so that the underlying writer can use sendfile
+// and similar optimizations.
+func (rf readerFrom) ReadFrom(r io.Reader) (int64, error) {
+	rf.w.wroteHeader = true
+	n, err := rf.w.ResponseWriter.(io.ReaderFrom).ReadFrom(r)
+	rf.w.size += n
+	return n, err
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httptracing_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/httptracing"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+var endpoint = &tracing.Endpoint{ServiceName: "test-service"}
+
+func newServer(tracer *mocktracer.Tracer, options *httptracing.ServerOptions) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		span, err := tracing.GetSpanFromContext(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		span.AddEvent("handled", nil)
+		io.WriteString(w, "hello")
+	})
+	mux.HandleFunc("GET /fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "failure", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("GET /copy", func(w http.ResponseWriter, r *http.Request) {
+		// io.Copy uses io.ReaderFrom of the writer, since LimitedReader does not implement io.WriterTo
+		io.Copy(w, io.LimitReader(strings.NewReader("0123456789"), 8))
+	})
+	mux.HandleFunc("GET /upgrade", func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+	})
+	mux.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+	if options == nil {
+		options = &httptracing.ServerOptions{}
+	}
+	options.Tracer = tracer
+	options.Endpoint = endpoint
+	return httptest.NewServer(httptracing.Middleware(mux, options))
+}
+
+func get(t *testing.T, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestMiddleware(t *testing.T) {
+	tracer := mocktracer.New()
+	server := newServer(tracer, nil)
+	defer server.Close()
+
+	resp, err := get(t, server.URL+"/users/123", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "HTTP GET", span.Name)
+	assert.Equal(t, endpoint, span.Service)
+	assert.Equal(t, tracing.SpanKindServer, span.Kind)
+	require.NotNil(t, span.Options.Peer)
+	assert.EqualValues(t, 127<<24|1, span.Options.Peer.IPv4)
+	assert.NotZero(t, span.Options.Peer.Port)
+
+	expected := map[string]interface{}{
+		httptracing.MethodKey:       "GET",
+		httptracing.RouteKey:        "GET /users/{id}",
+		httptracing.StatusCodeKey:   int64(200),
+		httptracing.ResponseSizeKey: int64(5),
+	}
+	for key, value := range expected {
+		actual, ok := span.Attribute(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, value, actual, key)
+	}
+	assert.Equal(t, "handled", span.Events()[0].Name)
+	assert.NoError(t, span.EndOptions().Error)
+}
+
+func TestMiddlewareReadFrom(t *testing.T) {
+	tracer := mocktracer.New()
+	server := newServer(tracer, nil)
+	defer server.Close()
+
+	resp, err := get(t, server.URL+"/copy", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	size, _ := spans[0].Attribute(httptracing.ResponseSizeKey)
+	assert.Equal(t, int64(8), size)
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	tracer := mocktracer.New()
+	server := newServer(tracer, nil)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = io.WriteString(conn, "GET /upgrade HTTP/1.1\r\nHost: test\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")
+	require.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	require.Eventually(t, func() bool { return len(tracer.FinishedSpans()) == 1 }, time.Second, time.Millisecond)
+	span := tracer.FinishedSpans()[0]
+	status, _ := span.Attribute(httptracing.StatusCodeKey)
+	assert.Equal(t, int64(http.StatusSwitchingProtocols), status)
+	assert.NoError(t, span.EndOptions().Error)
+}
+
+// plainWriter implements none of the optional interfaces of http.ResponseWriter.
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestMiddlewareOptionalInterfaces(t *testing.T) {
+	tests := []struct {
+		name                          string
+		writer                        http.ResponseWriter
+		flusher, hijacker, readerFrom bool
+	}{
+		{"plain", plainWriter{httptest.NewRecorder()}, false, false, false},
+		{"recorder", httptest.NewRecorder(), true, false, false},
+	}
+	for _, test := range tests {
+		tracer := mocktracer.New()
+		handler := httptracing.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok := w.(http.Flusher)
+			assert.Equal(t, test.flusher, ok, test.name)
+			_, ok = w.(http.Hijacker)
+			assert.Equal(t, test.hijacker, ok, test.name)
+			_, ok = w.(io.ReaderFrom)
+			assert.Equal(t, test.readerFrom, ok, test.name)
+			assert.ErrorIs(t, http.NewResponseController(w).EnableFullDuplex(), http.ErrNotSupported, test.name)
+			io.Copy(w, io.LimitReader(strings.NewReader("0123456789"), 8))
+		}), &httptracing.ServerOptions{Tracer: tracer, Endpoint: endpoint})
+		handler.ServeHTTP(test.writer, httptest.NewRequest("GET", "/", nil))
+
+		spans := tracer.FinishedSpans()
+		require.Len(t, spans, 1, test.name)
+		size, _ := spans[0].Attribute(httptracing.ResponseSizeKey)
+		assert.Equal(t, int64(8), size, test.name)
+	}
+}
+
+func TestMiddlewareJoinsTrace(t *testing.T) {
+	tracer := mocktracer.New()
+	server := newServer(tracer, &httptracing.ServerOptions{
+		Propagator: httptracing.HeaderPropagator("Trace-Me"),
+		SpanName: func(r *http.Request) string {
+			return "users"
+		},
+	})
+	defer server.Close()
+
+	clientID := tracer.CreateSpanID(10, 20, 0, 1)
+	header := http.Header{"Trace-Me": {tracer.GetStringPickler().ToString(clientID)}}
+	_, err := get(t, server.URL+"/users/123", header)
+	require.NoError(t, err)
+
+	// the malformed header starts a new trace
+	header = http.Header{"Trace-Me": {"malformed"}}
+	_, err = get(t, server.URL+"/users/123", header)
+	require.NoError(t, err)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "users", spans[0].Name)
+	assert.Equal(t, clientID, spans[0].SpanID())
+	assert.NotEqual(t, clientID.TraceID(), spans[1].ID.TraceID())
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	tracer := mocktracer.New()
+	server := newServer(tracer, nil)
+	defer server.Close()
+
+	resp, err := get(t, server.URL+"/fail", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	_, err = get(t, server.URL+"/panic", nil)
+	assert.Error(t, err)
+
+	// the client may retry the aborted GET request
+	spans := tracer.FinishedSpans()
+	require.True(t, len(spans) >= 2)
+	assert.EqualError(t, spans[0].EndOptions().Error, "HTTP status 503")
+	status, _ := spans[0].Attribute(httptracing.StatusCodeKey)
+	assert.Equal(t, int64(503), status)
+	for _, span := range spans[1:] {
+		assert.EqualError(t, span.EndOptions().Error, "panic: "+http.ErrAbortHandler.Error())
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// InvalidSpanRecordError is returned by ImportSpans() when a span record fails validation.
+// The actual error wraps it with the details of the offending record.
+var InvalidSpanRecordError = errors.New("Invalid span record")
+
+// Attribute is a key/value pair attached to a span, as in Span.AddAttribute().
+type Attribute struct {
+	Name  string
+	Value interface{}
+}
+
+// EventRecord is an event that was recorded outside of the tracer.
+type EventRecord struct {
+	Name      string
+	Timestamp time.Time
+}
+
+// SpanRecord describes a span that was recorded outside of the tracer, by a component that could not
+// report it directly, such as a mobile application. IDs use the Zipkin-compatible representation.
+type SpanRecord struct {
+	// TraceID is the ID of the trace the span belongs to. Must not be 0.
+	TraceID int64
+
+	// ID is the ID of the span, unique within the trace. Must not be 0.
+	ID int64
+
+	// ParentID is the ID of the parent span, or 0 for a root span. The parent may be part of the same batch,
+	// or a span recorded elsewhere, e.g. the server span that received the batch.
+	ParentID int64
+
+	// Flags are the Zipkin-compatible trace flags, such as the sampling flag.
+	Flags byte
+
+	// Name is the name of the span. Must not be empty.
+	Name string
+
+	// Service is the endpoint of the service that recorded the span.
+	Service *Endpoint
+
+	// Kind, LocalComponent, Async and Peer have the same meaning as in BeginOptions.
+	Kind           SpanKind
+	LocalComponent string
+	Async          bool
+	Peer           *Endpoint
+
+	// Start is the start timestamp of the span. Must not be zero.
+	Start time.Time
+
+	// Duration of the span. Must not be negative.
+	Duration time.Duration
+
+	// Attributes and Events are added to the span in the given order.
+	Attributes []Attribute
+	Events     []EventRecord
+
+	// Error indicates that the span finished with an error, as in EndOptions.
+	Error error
+}
+
+// SpanImporter can be implemented by tracers that are able to ingest externally recorded spans natively.
+type SpanImporter interface {
+	// ImportSpans feeds a validated batch of span records to the tracer's reporting pipeline.
+	ImportSpans(records []SpanRecord) error
+}
+
+// ImportSpans validates a batch of externally recorded spans and feeds them to the tracer as if the tracer had
+// produced them. If any record is invalid, nothing is imported and an error wrapping InvalidSpanRecordError
+// is returned.
+//
+// The global tracer and the decorators of this package, such as NewDebugTracer(), are unwrapped to find the
+// capabilities of the tracer they forward to. Tracers implementing SpanImporter receive the batch directly,
+// bypassing the decorators. With tracers implementing ZipkinCompatibleTracer every span is started via
+// JoinTrace() with the span ID built from the record, which preserves the original IDs. Other tracers get the
+// spans replayed through BeginTrace() and BeginChildSpan(); the tree structure within the batch is preserved,
+// but the tracer assigns new IDs, and spans whose parent is not part of the batch start new traces.
+// Either way, records without an explicit Kind are resolved by ResolveKind() as if they were started with
+// BeginChildSpan() when their parent is part of the batch, with JoinTrace() when the parent is not, and with
+// BeginTrace() when they have no parent.
+func ImportSpans(tracer Tracer, records []SpanRecord) error {
+	order, err := sortSpanRecords(records)
+	if err != nil {
+		return err
+	}
+	var zipkinTracer ZipkinCompatibleTracer
+	for t := tracer; t != nil; t = unwrapTracer(t) {
+		if importer, ok := t.(SpanImporter); ok {
+			return importer.ImportSpans(records)
+		}
+		// the global tracer only forwards CreateSpanID(), the tracers it unwraps to decide
+		if _, global := t.(*globalTracer); global {
+			continue
+		}
+		if zt, ok := t.(ZipkinCompatibleTracer); ok && zipkinTracer == nil {
+			zipkinTracer = zt
+		}
+	}
+
+	spans := make(map[spanRecordKey]Span, len(records))
+	for _, i := range order {
+		r := &records[i]
+		start := r.Start
+		options := &BeginOptions{
+			TimeOption:     TimeOption{Timestamp: &start},
+			Kind:           r.Kind,
+			LocalComponent: r.LocalComponent,
+			Async:          r.Async,
+			Peer:           r.Peer,
+		}
+		parent, inBatch := spans[spanRecordKey{r.TraceID, r.ParentID}]
+		switch {
+		case inBatch:
+			options.Kind = options.ResolveKind(SpanStartChild)
+		case r.ParentID != 0:
+			options.Kind = options.ResolveKind(SpanStartJoin)
+		default:
+			options.Kind = options.ResolveKind(SpanStartTrace)
+		}
+		var span Span
+		if zipkinTracer != nil {
+			spanID := zipkinTracer.CreateSpanID(r.TraceID, r.ID, r.ParentID, r.Flags)
+			span = tracer.JoinTrace(r.Name, r.Service, spanID, options)
+		} else if inBatch {
+			span = parent.BeginChildSpan(r.Name, options)
+		} else {
+			span = tracer.BeginTrace(r.Name, r.Service, options)
+		}
+		spans[spanRecordKey{r.TraceID, r.ID}] = span
+	}
+
+	// end the children before their parents
+	for j := len(order) - 1; j >= 0; j-- {
+		r := &records[order[j]]
+		span := spans[spanRecordKey{r.TraceID, r.ID}]
+		for _, a := range r.Attributes {
+			span.AddAttribute(a.Name, a.Value)
+		}
+		for _, e := range r.Events {
+			ts := e.Timestamp
+			span.AddEvent(e.Name, &EventOptions{TimeOption: TimeOption{Timestamp: &ts}})
+		}
+		duration := r.Duration
+		span.End(&EndOptions{Duration: &duration, Error: r.Error})
+	}
+	return nil
+}
+
+// tracerWrapper is implemented by the tracers of this package that forward to another tracer.
+type tracerWrapper interface {
+	wrappedTracer() Tracer
+}
+
+// unwrapTracer returns the tracer wrapped by the tracer, or nil if it does not wrap one.
+func unwrapTracer(tracer Tracer) Tracer {
+	if w, ok := tracer.(tracerWrapper); ok {
+		return w.wrappedTracer()
+	}
+	return nil
+}
+
+type spanRecordKey struct {
+	traceID int64
+	id      int64
+}
+
+// sortSpanRecords validates the records and returns their indexes ordered so that parents come before children.
+func sortSpanRecords(records []SpanRecord) ([]int, error) {
+	index := make(map[spanRecordKey]int, len(records))
+	for i := range records {
+		r := &records[i]
+		if err := validateSpanRecord(r); err != nil {
+			return nil, fmt.Errorf("%w: record %d (%q): %s", InvalidSpanRecordError, i, r.Name, err)
+		}
+		key := spanRecordKey{r.TraceID, r.ID}
+		if _, ok := index[key]; ok {
+			return nil, fmt.Errorf("%w: record %d (%q): duplicate span ID %x", InvalidSpanRecordError, i, r.Name, r.ID)
+		}
+		index[key] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(records))
+	order := make([]int, 0, len(records))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: record %d (%q): parent cycle", InvalidSpanRecordError, i, records[i].Name)
+		}
+		state[i] = visiting
+		r := &records[i]
+		if parent, ok := index[spanRecordKey{r.TraceID, r.ParentID}]; ok && r.ParentID != 0 {
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		order = append(order, i)
+		return nil
+	}
+	for i := range records {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func validateSpanRecord(r *SpanRecord) error {
+	switch {
+	case r.Name == "":
+		return errors.New("empty name")
+	case r.TraceID == 0:
+		return errors.New("missing trace ID")
+	case r.ID == 0:
+		return errors.New("missing span ID")
+	case r.ID == r.ParentID:
+		return errors.New("span is its own parent")
+	case r.Start.IsZero():
+		return errors.New("missing start timestamp")
+	case r.Duration < 0:
+		return errors.New("negative duration")
+	}
+	for _, e := range r.Events {
+		if e.Name == "" || e.Timestamp.IsZero() {
+			return fmt.Errorf("event %q without name or timestamp", e.Name)
+		}
+	}
+	return nil
+}
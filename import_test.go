@@ -0,0 +1,206 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+var importStart = time.Date(2015, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func importRecords() []tracing.SpanRecord {
+	mobile := &tracing.Endpoint{ServiceName: "mobile-app"}
+	// children are deliberately listed before their parents
+	return []tracing.SpanRecord{
+		{
+			TraceID: 7, ID: 3, ParentID: 2, Flags: 1, Name: "http-call", Service: mobile,
+			Kind: tracing.SpanKindClient, Start: importStart.Add(time.Second), Duration: time.Second,
+			Events: []tracing.EventRecord{{Name: "sent", Timestamp: importStart.Add(time.Second)}},
+			Error:  errors.New("timeout"),
+		},
+		{
+			TraceID: 7, ID: 2, ParentID: 1, Flags: 1, Name: "render", Service: mobile,
+			LocalComponent: "ui", Start: importStart, Duration: 3 * time.Second,
+			Attributes: []tracing.Attribute{{Name: "screen", Value: "home"}},
+		},
+		{
+			TraceID: 7, ID: 1, Flags: 1, Name: "app-start", Service: mobile,
+			Start: importStart, Duration: 5 * time.Second,
+		},
+	}
+}
+
+func TestImportSpansZipkin(t *testing.T) {
+	mock := mocktracer.New()
+	require.NoError(t, tracing.ImportSpans(mock, importRecords()))
+	assertZipkinImport(t, mock)
+}
+
+func TestImportSpansZipkinWrapped(t *testing.T) {
+	mock := mocktracer.New()
+	tracing.SetGlobalTracer(tracing.NewLimitedTracer(mock, tracing.Limits{}))
+	defer tracing.SetGlobalTracer(nil)
+	require.NoError(t, tracing.ImportSpans(tracing.GlobalTracer(), importRecords()))
+	assertZipkinImport(t, mock)
+}
+
+func assertZipkinImport(t *testing.T, mock *mocktracer.Tracer) {
+
+	spans := mock.FinishedSpans()
+	require.Len(t, spans, 3)
+	byName := make(map[string]*mocktracer.Span)
+	for _, s := range spans {
+		byName[s.Name] = s
+		assert.EqualValues(t, 7, s.ID.TraceID())
+		assert.True(t, s.ID.IsSampled())
+		assert.Equal(t, "mobile-app", s.Service.ServiceName)
+	}
+	assert.Equal(t, "app-start", spans[0].Name, "parents must be started first")
+
+	call := byName["http-call"]
+	assert.EqualValues(t, 3, call.ID.ID())
+	assert.EqualValues(t, 2, call.ID.ParentID())
+	assert.Equal(t, tracing.SpanKindClient, call.Kind)
+	assert.Equal(t, importStart.Add(time.Second), *call.Options.Timestamp)
+	assert.Equal(t, time.Second, *call.EndOptions().Duration)
+	assert.EqualError(t, call.EndOptions().Error, "timeout")
+	require.Len(t, call.Events(), 1)
+	assert.Equal(t, importStart.Add(time.Second), *call.Events()[0].Options.Timestamp)
+
+	render := byName["render"]
+	assert.Equal(t, tracing.SpanKindInternal, render.Kind)
+	screen, _ := render.Attribute("screen")
+	assert.Equal(t, "home", screen)
+}
+
+func TestImportSpansReplay(t *testing.T) {
+	mock := mocktracer.New()
+	// the embedding hides ZipkinCompatibleTracer of the mock
+	tracer := struct{ tracing.Tracer }{mock}
+	require.NoError(t, tracing.ImportSpans(tracer, importRecords()))
+
+	spans := mock.FinishedSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "app-start", spans[0].Name)
+	assert.Nil(t, spans[0].Parent)
+	assert.Equal(t, "render", spans[1].Name)
+	assert.Equal(t, spans[0], spans[1].Parent)
+	assert.Equal(t, "http-call", spans[2].Name)
+	assert.Equal(t, spans[1], spans[2].Parent)
+	assert.Equal(t, 5*time.Second, *spans[0].EndOptions().Duration)
+	assert.Equal(t, tracing.SpanKindServer, spans[0].Kind)
+	assert.Equal(t, tracing.SpanKindInternal, spans[1].Kind)
+	assert.Equal(t, tracing.SpanKindClient, spans[2].Kind)
+
+	// the global tracer implements ZipkinCompatibleTracer, but the registered tracer does not
+	mock.Reset()
+	tracing.SetGlobalTracer(tracer)
+	defer tracing.SetGlobalTracer(nil)
+	require.NoError(t, tracing.ImportSpans(tracing.GlobalTracer(), importRecords()))
+	spans = mock.FinishedSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, spans[0], spans[1].Parent)
+	assert.Equal(t, spans[1], spans[2].Parent)
+}
+
+func TestImportSpansKind(t *testing.T) {
+	records := []tracing.SpanRecord{
+		{TraceID: 7, ID: 1, Name: "root", Start: importStart},
+		{TraceID: 7, ID: 2, ParentID: 1, Name: "child", Start: importStart},
+		{TraceID: 7, ID: 4, ParentID: 3, Name: "orphan", Start: importStart},
+		{TraceID: 8, ID: 1, Name: "caller", Peer: endpoint, Start: importStart},
+		{TraceID: 8, ID: 2, ParentID: 1, Name: "handler", Kind: tracing.SpanKindServer, Start: importStart},
+		{TraceID: 8, ID: 6, ParentID: 5, Name: "internal", LocalComponent: "db", Start: importStart},
+	}
+	mock := mocktracer.New()
+	tracers := map[string]tracing.Tracer{"zipkin": mock, "replay": struct{ tracing.Tracer }{mock}}
+	for name, tracer := range tracers {
+		mock.Reset()
+		require.NoError(t, tracing.ImportSpans(tracer, records), name)
+		kinds := make(map[string]tracing.SpanKind)
+		for _, s := range mock.FinishedSpans() {
+			kinds[s.Name] = s.Kind
+		}
+		assert.Equal(t, map[string]tracing.SpanKind{
+			"root":     tracing.SpanKindServer,
+			"child":    tracing.SpanKindClient,
+			"orphan":   tracing.SpanKindServer,
+			"caller":   tracing.SpanKindClient,
+			"handler":  tracing.SpanKindServer,
+			"internal": tracing.SpanKindInternal,
+		}, kinds, name)
+	}
+}
+
+type nativeImporter struct {
+	tracing.Tracer
+	records []tracing.SpanRecord
+}
+
+func (n *nativeImporter) ImportSpans(records []tracing.SpanRecord) error {
+	n.records = records
+	return nil
+}
+
+func TestImportSpansNative(t *testing.T) {
+	importer := &nativeImporter{Tracer: tracing.NewNoopTracer()}
+	records := importRecords()
+	require.NoError(t, tracing.ImportSpans(importer, records))
+	assert.Equal(t, records, importer.records)
+
+	importer.records = nil
+	tracing.SetGlobalTracer(tracing.NewDebugTracer(importer, nil))
+	defer tracing.SetGlobalTracer(nil)
+	require.NoError(t, tracing.ImportSpans(tracing.GlobalTracer(), records))
+	assert.Equal(t, records, importer.records)
+}
+
+func TestImportSpansValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(records []tracing.SpanRecord)
+	}{
+		{"empty name", func(r []tracing.SpanRecord) { r[0].Name = "" }},
+		{"missing trace ID", func(r []tracing.SpanRecord) { r[0].TraceID = 0 }},
+		{"missing span ID", func(r []tracing.SpanRecord) { r[0].ID = 0 }},
+		{"own parent", func(r []tracing.SpanRecord) { r[0].ParentID = r[0].ID }},
+		{"missing start", func(r []tracing.SpanRecord) { r[0].Start = time.Time{} }},
+		{"negative duration", func(r []tracing.SpanRecord) { r[0].Duration = -time.Second }},
+		{"bad event", func(r []tracing.SpanRecord) { r[0].Events[0].Timestamp = time.Time{} }},
+		{"duplicate ID", func(r []tracing.SpanRecord) { r[1].ID = r[0].ID; r[1].ParentID = 1 }},
+		{"cycle", func(r []tracing.SpanRecord) { r[2].ParentID = 3 }},
+	}
+	for _, test := range tests {
+		mock := mocktracer.New()
+		records := importRecords()
+		test.modify(records)
+		err := tracing.ImportSpans(mock, records)
+		assert.True(t, errors.Is(err, tracing.InvalidSpanRecordError), test.name)
+		assert.Empty(t, mock.Spans(), test.name)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package mocktracer provides a tracing.Tracer that records spans in memory, so that the tests of this
+// module can verify the instrumentation without a real tracing system.
+package mocktracer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+// Tracer is a tracing.Tracer that keeps every span it creates in memory. It also implements
+// tracing.ZipkinCompatibleTracer.
+type Tracer struct {
+	mu     sync.Mutex
+	spans  []*Span
+	nextID int64
+	closed bool
+}
+
+// New creates an empty mock tracer.
+func New() *Tracer {
+	return &Tracer{}
+}
+
+// BeginTrace implements BeginTrace() of tracing.Tracer
+func (t *Tracer) BeginTrace(spanName string, service *tracing.Endpoint, options *tracing.BeginOptions) tracing.Span {
+	id := t.newID()
+	return t.start(spanName, service, &SpanID{traceID: id, id: id, flags: 1}, nil, tracing.SpanStartTrace, options)
+}
+
+// JoinTrace implements JoinTrace() of tracing.Tracer
+func (t *Tracer) JoinTrace(spanName string, service *tracing.Endpoint, spanID tracing.SpanID, options *tracing.BeginOptions) tracing.Span {
+	id, ok := spanID.(*SpanID)
+	if !ok {
+		return t.BeginTrace(spanName, service, options)
+	}
+	return t.start(spanName, service, id, nil, tracing.SpanStartJoin, options)
+}
+
+// GetStringPickler implements GetStringPickler() of tracing.Tracer
+func (t *Tracer) GetStringPickler() tracing.StringPickler {
+	return pickler{}
+}
+
+// Close implements Close() of tracing.Tracer
+func (t *Tracer) Close() {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+}
+
+// CreateSpanID implements CreateSpanID() of tracing.ZipkinCompatibleTracer
+func (t *Tracer) CreateSpanID(traceID, spanID, parentID int64, flags byte) tracing.ZipkinSpanID {
+	return &SpanID{traceID: traceID, id: spanID, parentID: parentID, flags: flags}
+}
+
+// Spans returns all spans created by the tracer, in the order they were started.
+func (t *Tracer) Spans() []*Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Span(nil), t.spans...)
+}
+
+// FinishedSpans returns the spans that have been ended, in the order they were started.
+func (t *Tracer) FinishedSpans() []*Span {
+	var spans []*Span
+	for _, s := range t.Spans() {
+		if s.Ended() {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// Closed returns whether Close() has been called on the tracer.
+func (t *Tracer) Closed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
+
+// Reset discards all recorded spans.
+func (t *Tracer) Reset() {
+	t.mu.Lock()
+	t.spans = nil
+	t.mu.Unlock()
+}
+
+func (t *Tracer) newID() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	return t.nextID
+}
+
+func (t *Tracer) start(name string, service *tracing.Endpoint, id *SpanID, parent *Span, start tracing.SpanStart,
+	options *tracing.BeginOptions) *Span {
+	s := &Span{tracer: t, Name: name, Service: service, ID: id, Parent: parent}
+	if options != nil {
+		s.Options = *options
+	}
+	s.Kind = options.ResolveKind(start)
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return s
+}
+
+// -----
+
+// SpanID is the span identifier used by the mock tracer. It implements tracing.ZipkinSpanID.
+type SpanID struct {
+	traceID  int64
+	id       int64
+	parentID int64
+	flags    byte
+}
+
+// String implements String() of tracing.SpanID
+func (id *SpanID) String() string {
+	return fmt.Sprintf("%x:%x:%x:%x", uint64(id.traceID), uint64(id.id), uint64(id.parentID), id.flags)
+}
+
+// TraceID implements TraceID() of tracing.ZipkinSpanID
+func (id *SpanID) TraceID() int64 {
+	return id.traceID
+}
+
+// ID implements ID() of tracing.ZipkinSpanID
+func (id *SpanID) ID() int64 {
+	return id.id
+}
+
+// ParentID implements ParentID() of tracing.ZipkinSpanID
+func (id *SpanID) ParentID() int64 {
+	return id.parentID
+}
+
+// IsSampled implements IsSampled() of tracing.ZipkinSpanID
+func (id *SpanID) IsSampled() bool {
+	return id.flags&1 == 1
+}
+
+// -----
+
+type pickler struct{}
+
+var errMalformedSpanID = errors.New("Malformed mock span ID")
+
+// ToString implements ToString() of tracing.StringPickler
+func (pickler) ToString(spanID tracing.SpanID) string {
+	if id, ok := spanID.(*SpanID); ok {
+		return id.String()
+	}
+	return ""
+}
+
+// FromString implements FromString() of tracing.StringPickler
+func (pickler) FromString(value string) (tracing.SpanID, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var traceID, id, parentID uint64
+	var flags byte
+	if n, err := fmt.Sscanf(value, "%x:%x:%x:%x", &traceID, &id, &parentID, &flags); err != nil || n != 4 {
+		return nil, errMalformedSpanID
+	}
+	return &SpanID{traceID: int64(traceID), id: int64(id), parentID: int64(parentID), flags: flags}, nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mocktracer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+func TestMockTracer(t *testing.T) {
+	tracer := mocktracer.New()
+	endpoint := &tracing.Endpoint{ServiceName: "test-service"}
+
+	root := tracer.BeginTrace("root", endpoint, &tracing.BeginOptions{LocalComponent: "lc"})
+	child := root.BeginChildSpan("child", nil)
+	child.AddAttribute("key", "v1")
+	child.AddAttribute("key", "v2")
+	child.AddEvent("event", nil)
+	child.End(nil)
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "lc", spans[0].Options.LocalComponent)
+	assert.Equal(t, spans[0], spans[1].Parent)
+	assert.Equal(t, endpoint, spans[1].Service)
+	assert.Equal(t, spans[0].ID.TraceID(), spans[1].ID.TraceID())
+	assert.Equal(t, spans[0].ID.ID(), spans[1].ID.ParentID())
+	assert.True(t, spans[1].ID.IsSampled())
+	assert.Equal(t, tracing.SpanKindInternal, spans[0].Kind)
+	assert.Equal(t, tracing.SpanKindClient, spans[1].Kind)
+
+	require.Len(t, tracer.FinishedSpans(), 1)
+	value, ok := spans[1].Attribute("key")
+	assert.True(t, ok)
+	assert.Equal(t, "v2", value)
+	assert.Len(t, spans[1].Attributes(), 2)
+	assert.Equal(t, "event", spans[1].Events()[0].Name)
+
+	tracer.Reset()
+	assert.Empty(t, tracer.Spans())
+	tracer.Close()
+	assert.True(t, tracer.Closed())
+}
+
+func TestMockPickler(t *testing.T) {
+	tracer := mocktracer.New()
+	pickler := tracer.GetStringPickler()
+
+	id := tracer.CreateSpanID(1, 2, 3, 1)
+	str := pickler.ToString(id)
+	parsed, err := pickler.FromString(str)
+	require.NoError(t, err)
+	assert.Equal(t, id, parsed)
+
+	parsed, err = pickler.FromString("")
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+
+	_, err = pickler.FromString("garbage")
+	assert.Error(t, err)
+
+	span := tracer.JoinTrace("server", nil, id, nil)
+	assert.Equal(t, id, span.SpanID())
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mocktracer
+
+import (
+	"sync"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+// Event is a marker recorded by Span.AddEvent().
+type Event struct {
+	// Name is the name of the event.
+	Name string
+
+	// Options is a copy of the options passed to AddEvent().
+	Options tracing.EventOptions
+}
+
+// Span is a tracing.Span recorded by the mock tracer.
+type Span struct {
+	tracer *Tracer
+
+	// Name is the name the span was started with.
+	Name string
+
+	// Service is the endpoint passed to BeginTrace() or JoinTrace(), or inherited from the parent span.
+	Service *tracing.Endpoint
+
+	// Options is a copy of the options the span was started with.
+	Options tracing.BeginOptions
+
+	// Kind is the kind of the span, resolved from Options.
+	Kind tracing.SpanKind
+
+	// ID is the identifier of the span.
+	ID *SpanID
+
+	// Parent is the span that created this span via BeginChildSpan(), nil for root and joined spans.
+	Parent *Span
+
+	mu         sync.Mutex
+	attributes []tracing.Attribute
+	events     []Event
+	ended      bool
+	endOptions tracing.EndOptions
+}
+
+// SpanID implements SpanID() of tracing.Span
+func (s *Span) SpanID() tracing.SpanID {
+	return s.ID
+}
+
+// BeginChildSpan implements BeginChildSpan() of tracing.Span
+func (s *Span) BeginChildSpan(name string, options *tracing.BeginOptions) tracing.Span {
+	id := &SpanID{traceID: s.ID.traceID, id: s.tracer.newID(), parentID: s.ID.id, flags: s.ID.flags}
+	return s.tracer.start(name, s.Service, id, s, tracing.SpanStartChild, options)
+}
+
+// End implements End() of tracing.Span
+func (s *Span) End(options *tracing.EndOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended = true
+	if options != nil {
+		s.endOptions = *options
+	}
+}
+
+// AddAttribute implements AddAttribute() of tracing.Span
+func (s *Span) AddAttribute(name string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, tracing.Attribute{Name: name, Value: value})
+}
+
+// AddEvent implements AddEvent() of tracing.Span
+func (s *Span) AddEvent(name string, options *tracing.EventOptions) {
+	event := Event{Name: name}
+	if options != nil {
+		event.Options = *options
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+// Attributes returns the attributes recorded on the span, in the order they were added.
+func (s *Span) Attributes() []tracing.Attribute {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]tracing.Attribute(nil), s.attributes...)
+}
+
+// Attribute returns the last value recorded for the given attribute name.
+func (s *Span) Attribute(name string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.attributes) - 1; i >= 0; i-- {
+		if s.attributes[i].Name == name {
+			return s.attributes[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// Events returns the events recorded on the span, in the order they were added.
+func (s *Span) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+// Ended returns whether End() has been called on the span.
+func (s *Span) Ended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ended
+}
+
+// EndOptions returns a copy of the options passed to End().
+func (s *Span) EndOptions() tracing.EndOptions {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.endOptions
+}
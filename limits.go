@@ -0,0 +1,184 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+// Names of the marker attributes added by the limited tracer when a span exceeded its limits.
+// The values are int64 counts of dropped or truncated items.
+const (
+	DroppedAttributesKey = "tracing.dropped_attributes"
+	DroppedEventsKey     = "tracing.dropped_events"
+	TruncatedValuesKey   = "tracing.truncated_values"
+)
+
+// Limits caps the amount of data a single span can carry. A zero value of any field means no limit.
+type Limits struct {
+	// MaxAttributes is the maximum number of attributes per span. Attributes past the limit are dropped.
+	MaxAttributes int
+
+	// MaxEvents is the maximum number of events per span. Events past the limit are dropped.
+	MaxEvents int
+
+	// MaxValueLength is the maximum length in bytes of a string attribute value. Longer values
+	// are truncated on a UTF-8 character boundary.
+	MaxValueLength int
+
+	// MaxBytesLength is the maximum size of a []byte attribute value. Longer values are truncated.
+	MaxBytesLength int
+}
+
+type limitedTracer struct {
+	tracer Tracer
+	limits Limits
+}
+
+type limitedSpan struct {
+	span   Span
+	limits *Limits
+
+	mu                sync.Mutex
+	attributes        int
+	events            int
+	droppedAttributes int64
+	droppedEvents     int64
+	truncatedValues   int64
+}
+
+// NewLimitedTracer creates a tracer that delegates to the given tracer and enforces the limits on every
+// span it creates, so that a misbehaving caller cannot produce unbounded payloads. When a span exceeded
+// any of the limits, marker attributes with the dropped and truncated counts are added to it on End().
+func NewLimitedTracer(tracer Tracer, limits Limits) Tracer {
+	return &limitedTracer{tracer: tracer, limits: limits}
+}
+
+// BeginTrace implements BeginTrace() of tracing.Tracer
+func (t *limitedTracer) BeginTrace(spanName string, service *Endpoint, options *BeginOptions) Span {
+	return &limitedSpan{span: t.tracer.BeginTrace(spanName, service, options), limits: &t.limits}
+}
+
+// JoinTrace implements JoinTrace() of tracing.Tracer
+func (t *limitedTracer) JoinTrace(spanName string, service *Endpoint, spanID SpanID, options *BeginOptions) Span {
+	return &limitedSpan{span: t.tracer.JoinTrace(spanName, service, spanID, options), limits: &t.limits}
+}
+
+func (t *limitedTracer) wrappedTracer() Tracer {
+	return t.tracer
+}
+
+// GetStringPickler implements GetStringPickler() of tracing.Tracer
+func (t *limitedTracer) GetStringPickler() StringPickler {
+	return t.tracer.GetStringPickler()
+}
+
+// Close implements Close() of tracing.Tracer
+func (t *limitedTracer) Close() {
+	t.tracer.Close()
+}
+
+// -----
+
+// SpanID implements SpanID() of tracing.Span
+func (s *limitedSpan) SpanID() SpanID {
+	return s.span.SpanID()
+}
+
+// BeginChildSpan implements BeginChildSpan() of tracing.Span
+func (s *limitedSpan) BeginChildSpan(name string, options *BeginOptions) Span {
+	return &limitedSpan{span: s.span.BeginChildSpan(name, options), limits: s.limits}
+}
+
+// End implements End() of tracing.Span
+func (s *limitedSpan) End(options *EndOptions) {
+	s.mu.Lock()
+	droppedAttributes, droppedEvents, truncatedValues := s.droppedAttributes, s.droppedEvents, s.truncatedValues
+	s.mu.Unlock()
+
+	if droppedAttributes > 0 {
+		s.span.AddAttribute(DroppedAttributesKey, droppedAttributes)
+	}
+	if droppedEvents > 0 {
+		s.span.AddAttribute(DroppedEventsKey, droppedEvents)
+	}
+	if truncatedValues > 0 {
+		s.span.AddAttribute(TruncatedValuesKey, truncatedValues)
+	}
+	s.span.End(options)
+}
+
+// AddAttribute implements AddAttribute() of tracing.Span
+func (s *limitedSpan) AddAttribute(name string, value interface{}) {
+	value, truncated := s.limits.truncate(value)
+
+	s.mu.Lock()
+	if s.limits.MaxAttributes > 0 && s.attributes >= s.limits.MaxAttributes {
+		s.droppedAttributes++
+		s.mu.Unlock()
+		return
+	}
+	s.attributes++
+	if truncated {
+		s.truncatedValues++
+	}
+	s.mu.Unlock()
+
+	s.span.AddAttribute(name, value)
+}
+
+// AddEvent implements AddEvent() of tracing.Span
+func (s *limitedSpan) AddEvent(name string, options *EventOptions) {
+	s.mu.Lock()
+	if s.limits.MaxEvents > 0 && s.events >= s.limits.MaxEvents {
+		s.droppedEvents++
+		s.mu.Unlock()
+		return
+	}
+	s.events++
+	s.mu.Unlock()
+
+	s.span.AddEvent(name, options)
+}
+
+func (s *limitedSpan) wrappedSpan() Span {
+	return s.span
+}
+
+// truncate shortens string and []byte values that exceed the limits, and reports whether it did.
+func (l *Limits) truncate(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		if l.MaxValueLength > 0 && len(v) > l.MaxValueLength {
+			n := l.MaxValueLength
+			for n > 0 && !utf8.RuneStart(v[n]) {
+				n--
+			}
+			return v[:n], true
+		}
+	case []byte:
+		if l.MaxBytesLength > 0 && len(v) > l.MaxBytesLength {
+			return v[:l.MaxBytesLength:l.MaxBytesLength], true
+		}
+	}
+	return value, false
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+func TestLimitedTracerCounts(t *testing.T) {
+	mock := mocktracer.New()
+	tracer := tracing.NewLimitedTracer(mock, tracing.Limits{MaxAttributes: 3, MaxEvents: 2})
+
+	span := tracer.BeginTrace("root", endpoint, nil)
+	for i := 0; i < 10; i++ {
+		span.AddAttribute(fmt.Sprintf("key-%d", i), int64(i))
+		span.AddEvent(fmt.Sprintf("event-%d", i), nil)
+	}
+	span.End(nil)
+
+	require.Len(t, mock.Spans(), 1)
+	recorded := mock.Spans()[0]
+	// 3 regular attributes + 2 markers
+	assert.Len(t, recorded.Attributes(), 5)
+	assert.Len(t, recorded.Events(), 2)
+
+	dropped, ok := recorded.Attribute(tracing.DroppedAttributesKey)
+	assert.True(t, ok)
+	assert.Equal(t, int64(7), dropped)
+	dropped, ok = recorded.Attribute(tracing.DroppedEventsKey)
+	assert.True(t, ok)
+	assert.Equal(t, int64(8), dropped)
+	_, ok = recorded.Attribute(tracing.TruncatedValuesKey)
+	assert.False(t, ok)
+}
+
+func TestLimitedTracerValues(t *testing.T) {
+	mock := mocktracer.New()
+	tracer := tracing.NewLimitedTracer(mock, tracing.Limits{MaxValueLength: 4, MaxBytesLength: 2})
+
+	spanID, err := mock.GetStringPickler().FromString("1:2:0:1")
+	require.NoError(t, err)
+	span := tracer.JoinTrace("server", endpoint, spanID, nil)
+	child := span.BeginChildSpan("child", nil)
+	child.AddAttribute("short", "abc")
+	child.AddAttribute("long", "abcdefgh")
+	child.AddAttribute("utf8", "abcé")
+	child.AddAttribute("bytes", []byte{1, 2, 3})
+	child.AddAttribute("int", int64(12345678))
+	child.End(nil)
+	span.End(nil)
+
+	spans := mock.Spans()
+	require.Len(t, spans, 2)
+	recorded := spans[1]
+	expected := map[string]interface{}{
+		"short": "abc",
+		"long":  "abcd",
+		"utf8":  "abc",
+		"bytes": []byte{1, 2},
+		"int":   int64(12345678),
+	}
+	for key, value := range expected {
+		actual, ok := recorded.Attribute(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, value, actual, key)
+	}
+	truncated, _ := recorded.Attribute(tracing.TruncatedValuesKey)
+	assert.Equal(t, int64(3), truncated)
+
+	// the parent did not exceed any limits
+	assert.Empty(t, spans[0].Attributes())
+}
+
+func TestLimitedTracerNoLimits(t *testing.T) {
+	mock := mocktracer.New()
+	tracer := tracing.NewLimitedTracer(mock, tracing.Limits{})
+
+	span := tracer.BeginTrace("root", endpoint, nil)
+	for i := 0; i < 100; i++ {
+		span.AddAttribute("key", "value")
+	}
+	span.End(nil)
+	assert.Len(t, mock.Spans()[0].Attributes(), 100)
+	assert.Equal(t, mock.GetStringPickler(), tracer.GetStringPickler())
+
+	tracer.Close()
+	assert.True(t, mock.Closed())
+}
@@ -18,7 +18,10 @@
 
 package tracing
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // TimeOption can be used to provide externally captured time and duration to span methods, e.g. when recording
 // spans produced by a component that could not emit them directly to the tracer, such as a mobile application.
@@ -28,10 +31,58 @@ type TimeOption struct {
 	Timestamp *time.Time
 }
 
+// SpanKind describes the role a span plays in an interaction between services.
+type SpanKind int
+
+const (
+	// SpanKindUnspecified lets the tracer derive the kind from the other BeginOptions, see ResolveKind().
+	SpanKindUnspecified SpanKind = iota
+
+	// SpanKindClient marks a span that makes a synchronous request to a remote server.
+	SpanKindClient
+
+	// SpanKindServer marks a span that handles a synchronous request from a remote client.
+	SpanKindServer
+
+	// SpanKindProducer marks a span that sends a message to a broker or a queue, without waiting for it to be processed.
+	SpanKindProducer
+
+	// SpanKindConsumer marks a span that receives a message sent by a producer.
+	SpanKindConsumer
+
+	// SpanKindInternal marks a local, in-process unit of work.
+	SpanKindInternal
+)
+
+// String returns the lowercase name of the span kind.
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindUnspecified:
+		return "unspecified"
+	case SpanKindClient:
+		return "client"
+	case SpanKindServer:
+		return "server"
+	case SpanKindProducer:
+		return "producer"
+	case SpanKindConsumer:
+		return "consumer"
+	case SpanKindInternal:
+		return "internal"
+	default:
+		return fmt.Sprintf("SpanKind(%d)", int(k))
+	}
+}
+
 // BeginOptions contains optional flags that can be passed to BeginChildSpan().
 type BeginOptions struct {
 	TimeOption
 
+	// Kind explicitly describes the role of the span. When unspecified, the tracer derives it from
+	// LocalComponent and from how the span is started, see ResolveKind(). The resolved kind is passed
+	// to the reporters and is available to the samplers as part of the options.
+	Kind SpanKind
+
 	// LocalComponent, marks the span as a local, in-process unit of work, such as a function call to a library.
 	// When this field is empty string, the span is considered to be an RPC span.
 	LocalComponent string
@@ -48,6 +99,40 @@ type BeginOptions struct {
 	Peer *Endpoint
 }
 
+// SpanStart tells ResolveKind() how a span is started.
+type SpanStart int
+
+const (
+	// SpanStartTrace is a span started with BeginTrace().
+	SpanStartTrace SpanStart = iota
+
+	// SpanStartJoin is a span started with JoinTrace().
+	SpanStartJoin
+
+	// SpanStartChild is a span started with BeginChildSpan().
+	SpanStartChild
+)
+
+// ResolveKind returns the explicit Kind of the span if it is set. Otherwise it derives the kind from the other
+// options and from how the span is started: spans with LocalComponent are internal, RPC spans started with
+// BeginChildSpan() are client spans, and so are spans started with BeginTrace() that have a Peer, since they
+// call that peer without a parent. The remaining RPC spans, i.e. those that join a trace or start one without
+// a Peer, are server spans. The method can be called on nil options.
+func (o *BeginOptions) ResolveKind(start SpanStart) SpanKind {
+	switch {
+	case o != nil && o.Kind != SpanKindUnspecified:
+		return o.Kind
+	case o != nil && o.LocalComponent != "":
+		return SpanKindInternal
+	case start == SpanStartChild:
+		return SpanKindClient
+	case start == SpanStartTrace && o != nil && o.Peer != nil:
+		return SpanKindClient
+	default:
+		return SpanKindServer
+	}
+}
+
 // EndOptions contains optional flags that can be passed to span.End() method.
 type EndOptions struct {
 	// Duration of the span calculated externally. If not specified, the tracer will calculate it as endTs - startTs.
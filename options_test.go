@@ -0,0 +1,78 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-common/opentracing-go"
+)
+
+func TestResolveKind(t *testing.T) {
+	peer := &tracing.BeginOptions{Peer: endpoint}
+	tests := []struct {
+		options  *tracing.BeginOptions
+		start    tracing.SpanStart
+		expected tracing.SpanKind
+	}{
+		{nil, tracing.SpanStartTrace, tracing.SpanKindServer},
+		{nil, tracing.SpanStartJoin, tracing.SpanKindServer},
+		{nil, tracing.SpanStartChild, tracing.SpanKindClient},
+		{peer, tracing.SpanStartTrace, tracing.SpanKindClient},
+		{peer, tracing.SpanStartJoin, tracing.SpanKindServer},
+		{peer, tracing.SpanStartChild, tracing.SpanKindClient},
+		{&tracing.BeginOptions{LocalComponent: "db"}, tracing.SpanStartTrace, tracing.SpanKindInternal},
+		{&tracing.BeginOptions{LocalComponent: "db", Peer: endpoint}, tracing.SpanStartTrace, tracing.SpanKindInternal},
+		{&tracing.BeginOptions{LocalComponent: "db"}, tracing.SpanStartChild, tracing.SpanKindInternal},
+		{&tracing.BeginOptions{Kind: tracing.SpanKindProducer}, tracing.SpanStartChild, tracing.SpanKindProducer},
+		{&tracing.BeginOptions{Kind: tracing.SpanKindConsumer}, tracing.SpanStartJoin, tracing.SpanKindConsumer},
+		{&tracing.BeginOptions{Kind: tracing.SpanKindServer, Peer: endpoint}, tracing.SpanStartTrace, tracing.SpanKindServer},
+		{&tracing.BeginOptions{Kind: tracing.SpanKindClient, LocalComponent: "db"}, tracing.SpanStartTrace, tracing.SpanKindClient},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.options.ResolveKind(test.start), "%+v start=%v", test.options, test.start)
+	}
+}
+
+func TestSpanKindZipkin(t *testing.T) {
+	tests := []struct {
+		kind       tracing.SpanKind
+		name       string
+		zipkinKind string
+		begin, end string
+	}{
+		{tracing.SpanKindUnspecified, "unspecified", "", "", ""},
+		{tracing.SpanKindClient, "client", "CLIENT", "cs", "cr"},
+		{tracing.SpanKindServer, "server", "SERVER", "sr", "ss"},
+		{tracing.SpanKindProducer, "producer", "PRODUCER", "ms", ""},
+		{tracing.SpanKindConsumer, "consumer", "CONSUMER", "mr", ""},
+		{tracing.SpanKindInternal, "internal", "", "", ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.name, test.kind.String())
+		assert.Equal(t, test.zipkinKind, tracing.ZipkinKind(test.kind), test.name)
+		begin, end := tracing.ZipkinCoreAnnotations(test.kind)
+		assert.Equal(t, test.begin, begin, test.name)
+		assert.Equal(t, test.end, end, test.name)
+	}
+	assert.Equal(t, "SpanKind(42)", tracing.SpanKind(42).String())
+}
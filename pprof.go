@@ -0,0 +1,140 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+)
+
+// Names of the runtime/pprof labels set by the profiler label tracer.
+const (
+	SpanNameLabel = "span"
+	TraceIDLabel  = "trace_id"
+)
+
+// ProfilerLabelOptions contains optional settings that can be passed to NewProfilerLabelTracer().
+type ProfilerLabelOptions struct {
+	// TraceID adds the TraceIDLabel with the hexadecimal trace ID for sampled spans with a ZipkinSpanID.
+	// Trace IDs have unbounded cardinality, so profiles should be collected for short periods with this option.
+	TraceID bool
+}
+
+type profilerTracer struct {
+	tracer  Tracer
+	options ProfilerLabelOptions
+}
+
+type profilerSpan struct {
+	Span
+	tracer *profilerTracer
+	name   string
+	async  bool
+
+	mu sync.Mutex
+	// restore is the context whose labels the goroutine gets back when the span ends, if it was labeled.
+	restore context.Context
+}
+
+// NewProfilerLabelTracer creates a tracer that delegates to the given tracer and labels the CPU profiles with
+// the spans, so that "go tool pprof -tagfocus" can break the profiles down by operation. When a span created by
+// this tracer is stored in a context via ContextWithSpan(), or started by one of the context helpers, the context
+// gets the SpanNameLabel, and so does the current goroutine until the span ends. The span must then be ended on
+// the same goroutine, which gets back the labels of the context the span was stored into. Async spans only label
+// the context: Go(), Group.Go() and the workers of Pool run their work under pprof.Do() with the context.
+func NewProfilerLabelTracer(tracer Tracer, options *ProfilerLabelOptions) Tracer {
+	t := &profilerTracer{tracer: tracer}
+	if options != nil {
+		t.options = *options
+	}
+	return t
+}
+
+// BeginTrace implements BeginTrace() of tracing.Tracer
+func (t *profilerTracer) BeginTrace(spanName string, service *Endpoint, options *BeginOptions) Span {
+	return t.wrap(spanName, options, t.tracer.BeginTrace(spanName, service, options))
+}
+
+// JoinTrace implements JoinTrace() of tracing.Tracer
+func (t *profilerTracer) JoinTrace(spanName string, service *Endpoint, spanID SpanID, options *BeginOptions) Span {
+	return t.wrap(spanName, options, t.tracer.JoinTrace(spanName, service, spanID, options))
+}
+
+func (t *profilerTracer) wrappedTracer() Tracer {
+	return t.tracer
+}
+
+func (t *profilerTracer) wrap(name string, options *BeginOptions, span Span) Span {
+	return &profilerSpan{Span: span, tracer: t, name: name, async: options != nil && options.Async}
+}
+
+// GetStringPickler implements GetStringPickler() of tracing.Tracer
+func (t *profilerTracer) GetStringPickler() StringPickler {
+	return t.tracer.GetStringPickler()
+}
+
+// Close implements Close() of tracing.Tracer
+func (t *profilerTracer) Close() {
+	t.tracer.Close()
+}
+
+// BeginChildSpan implements BeginChildSpan() of tracing.Span
+func (s *profilerSpan) BeginChildSpan(name string, options *BeginOptions) Span {
+	return s.tracer.wrap(name, options, s.Span.BeginChildSpan(name, options))
+}
+
+// End implements End() of tracing.Span. The goroutine that was labeled with the span gets its labels back.
+func (s *profilerSpan) End(options *EndOptions) {
+	s.mu.Lock()
+	restore := s.restore
+	s.restore = nil
+	s.mu.Unlock()
+	if restore != nil {
+		pprof.SetGoroutineLabels(restore)
+	}
+	s.Span.End(options)
+}
+
+// attachToContext adds the labels of the span to the context, and applies them to the current goroutine until
+// the span ends, unless the span is Async.
+func (s *profilerSpan) attachToContext(ctx context.Context) context.Context {
+	labeled := pprof.WithLabels(ctx, pprof.Labels(s.labels()...))
+	if s.async {
+		return labeled
+	}
+	s.mu.Lock()
+	if s.restore == nil {
+		s.restore = ctx
+	}
+	s.mu.Unlock()
+	pprof.SetGoroutineLabels(labeled)
+	return labeled
+}
+
+func (s *profilerSpan) labels() []string {
+	labels := []string{SpanNameLabel, s.name}
+	if id, ok := s.SpanID().(ZipkinSpanID); ok && s.tracer.options.TraceID && id.IsSampled() {
+		labels = append(labels, TraceIDLabel, strconv.FormatUint(uint64(id.TraceID()), 16))
+	}
+	return labels
+}
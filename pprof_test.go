@@ -0,0 +1,116 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+// goroutineLabels returns the goroutine profile, which lists the labels of every goroutine.
+func goroutineLabels(t *testing.T) string {
+	var buf bytes.Buffer
+	require.NoError(t, pprof.Lookup("goroutine").WriteTo(&buf, 1))
+	return buf.String()
+}
+
+func TestProfilerLabels(t *testing.T) {
+	mock := mocktracer.New()
+	tracer := tracing.NewProfilerLabelTracer(mock, nil)
+
+	root, ctx := tracing.StartSpanFromContext(context.Background(), tracer, "root", nil)
+	label, ok := pprof.Label(ctx, tracing.SpanNameLabel)
+	assert.True(t, ok)
+	assert.Equal(t, "root", label)
+	_, ok = pprof.Label(ctx, tracing.TraceIDLabel)
+	assert.False(t, ok)
+	assert.Contains(t, goroutineLabels(t), `"span":"root"`, "the current goroutine is labeled")
+
+	child := root.BeginChildSpan("child", nil)
+	childCtx := tracing.ContextWithSpan(ctx, child)
+	label, _ = pprof.Label(childCtx, tracing.SpanNameLabel)
+	assert.Equal(t, "child", label)
+	assert.Contains(t, goroutineLabels(t), `"span":"child"`)
+	child.End(nil)
+	labels := goroutineLabels(t)
+	assert.NotContains(t, labels, `"span":"child"`)
+	assert.Contains(t, labels, `"span":"root"`, "the labels of the parent are restored")
+
+	done := make(chan struct{})
+	tracing.Go(ctx, "worker", func(ctx context.Context) {
+		defer close(done)
+		assert.Contains(t, goroutineLabels(t), `"span":"worker"`)
+	})
+	<-done
+	require.Eventually(t, func() bool { return len(mock.FinishedSpans()) == 2 }, time.Second, time.Millisecond)
+	assert.NotContains(t, goroutineLabels(t), `"span":"worker"`, "async spans do not label the calling goroutine")
+
+	root.End(nil)
+	assert.Len(t, mock.FinishedSpans(), 3)
+	assert.NotContains(t, goroutineLabels(t), `"span":"root"`)
+}
+
+func TestProfilerLabelsDecorated(t *testing.T) {
+	mock := mocktracer.New()
+	tracer := tracing.NewDebugTracer(tracing.NewProfilerLabelTracer(mock, nil), nil)
+
+	span, ctx := tracing.StartSpanFromContext(context.Background(), tracer, "root", nil)
+	label, _ := pprof.Label(ctx, tracing.SpanNameLabel)
+	assert.Equal(t, "root", label)
+
+	ctx = tracing.ContextWithSpan(context.Background(), tracing.WatchContext(ctx, span))
+	label, _ = pprof.Label(ctx, tracing.SpanNameLabel)
+	assert.Equal(t, "root", label)
+	assert.Contains(t, goroutineLabels(t), `"span":"root"`)
+	span.End(nil)
+	assert.NotContains(t, goroutineLabels(t), `"span":"root"`)
+}
+
+func TestProfilerLabelsTraceID(t *testing.T) {
+	mock := mocktracer.New()
+	tracer := tracing.NewProfilerLabelTracer(mock, &tracing.ProfilerLabelOptions{TraceID: true})
+
+	sampled := tracer.JoinTrace("sampled", endpoint, mock.CreateSpanID(0xabc, 1, 0, 1), nil)
+	ctx := tracing.ContextWithSpan(context.Background(), sampled)
+	label, ok := pprof.Label(ctx, tracing.TraceIDLabel)
+	assert.True(t, ok)
+	assert.Equal(t, "abc", label)
+	sampled.End(nil)
+
+	unsampled := tracer.JoinTrace("unsampled", endpoint, mock.CreateSpanID(0xabc, 2, 0, 0), nil)
+	ctx = tracing.ContextWithSpan(context.Background(), unsampled)
+	_, ok = pprof.Label(ctx, tracing.TraceIDLabel)
+	assert.False(t, ok)
+	unsampled.End(nil)
+
+	// spans of other tracers are stored without labels
+	ctx = tracing.ContextWithSpan(context.Background(), mock.BeginTrace("plain", endpoint, nil))
+	_, ok = pprof.Label(ctx, tracing.SpanNameLabel)
+	assert.False(t, ok)
+}
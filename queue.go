@@ -0,0 +1,173 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Names of the attributes and events recorded on the spans of jobs started with QueuedJob.Start().
+const (
+	// QueueEnqueuedEvent is the event marking the time the job was enqueued.
+	QueueEnqueuedEvent = "enqueued"
+
+	// QueueDequeuedEvent is the event marking the time the job was taken from the queue.
+	QueueDequeuedEvent = "dequeued"
+
+	// QueueDepthKey is the attribute holding the number of jobs waiting in the queue when the job was enqueued.
+	QueueDepthKey = "queue.depth"
+
+	// QueueWaitKey is the attribute holding the time in microseconds the job spent waiting in the queue.
+	QueueWaitKey = "queue.wait_us"
+)
+
+var PoolClosedError = errors.New("Pool is closed")
+
+// QueueOptions contains optional settings that can be passed to Enqueue() and NewPool().
+type QueueOptions struct {
+	// Clock measures the time jobs spend in the queue. If nil, the system clock is used.
+	Clock Clock
+}
+
+func (o *QueueOptions) clock() Clock {
+	if o == nil || o.Clock == nil {
+		return NewSystemClock()
+	}
+	return o.Clock
+}
+
+// QueuedJob is an envelope carrying a value through a work queue together with the tracing context
+// of the code that enqueued it, so that the work can be traced as part of the same trace.
+type QueuedJob[T any] struct {
+	// Value is the job payload.
+	Value T
+
+	ctx        context.Context
+	clock      Clock
+	enqueuedAt time.Time
+	depth      int
+}
+
+// Enqueue sends the value to the queue in an envelope capturing the current span and tracer in the context.
+// It blocks until the queue accepts the job or the context is done, in which case the cause of the context
+// is returned. The job keeps the values of the context, but not its cancellation, so that it can outlive
+// the enqueuing request.
+func Enqueue[T any](ctx context.Context, queue chan<- QueuedJob[T], value T, options *QueueOptions) error {
+	clock := options.clock()
+	job := QueuedJob[T]{
+		Value:      value,
+		ctx:        context.WithoutCancel(ctx),
+		clock:      clock,
+		enqueuedAt: clock.Now(),
+		depth:      len(queue),
+	}
+	select {
+	case queue <- job:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// Start is called by the worker that took the job from the queue. It starts an Async child span of the span
+// that was current when the job was enqueued, or a root span if there was none, see StartSpan(). The span gets
+// the QueueEnqueuedEvent and QueueDequeuedEvent events, and the QueueDepthKey and QueueWaitKey attributes.
+// The span starts at the time the job was enqueued, so that the time spent in the queue is part of it.
+// The returned context stores the span and should be used for processing the job.
+func (j QueuedJob[T]) Start(spanName string) (Span, context.Context) {
+	ctx := j.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	clock := j.clock
+	if clock == nil {
+		clock = NewSystemClock()
+	}
+	dequeuedAt := clock.Now()
+	options := &BeginOptions{Async: true}
+	if !j.enqueuedAt.IsZero() {
+		enqueued := Timestamp(j.enqueuedAt)
+		options.Timestamp = &enqueued
+	}
+	span, ctx := StartSpan(ctx, spanName, options)
+
+	if options.Timestamp != nil {
+		span.AddEvent(QueueEnqueuedEvent, &EventOptions{TimeOption: TimeOption{Timestamp: options.Timestamp}})
+		span.AddAttribute(QueueDepthKey, int64(j.depth))
+		span.AddAttribute(QueueWaitKey, int64(dequeuedAt.Sub(j.enqueuedAt)/time.Microsecond))
+	}
+	dequeued := Timestamp(dequeuedAt)
+	span.AddEvent(QueueDequeuedEvent, &EventOptions{TimeOption: TimeOption{Timestamp: &dequeued}})
+	return span, ctx
+}
+
+// Pool is a fixed number of worker goroutines processing jobs from a traced queue.
+type Pool[T any] struct {
+	queue   chan QueuedJob[T]
+	options *QueueOptions
+	wg      sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewPool starts the given number of workers that call handler for the jobs submitted to the pool. The queue
+// holds up to capacity jobs that are not yet picked up by the workers. Every job is handled in a span with
+// the given name started by QueuedJob.Start(), and ended with the error returned by the handler. Panics
+// are recorded and re-raised as in Go().
+func NewPool[T any](spanName string, workers int, capacity int, handler func(ctx context.Context, value T) error, options *QueueOptions) *Pool[T] {
+	p := &Pool[T]{queue: make(chan QueuedJob[T], capacity), options: options}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for job := range p.queue {
+				span, ctx := job.Start(spanName)
+				runInSpan(ctx, span, func(ctx context.Context) error { return handler(ctx, job.Value) })
+			}
+		}()
+	}
+	return p
+}
+
+// Submit enqueues the value for processing, see Enqueue(). It returns PoolClosedError if the pool is closed.
+func (p *Pool[T]) Submit(ctx context.Context, value T) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return PoolClosedError
+	}
+	return Enqueue(ctx, p.queue, value, p.options)
+}
+
+// Close stops accepting new jobs and waits for the workers to process the jobs already in the queue.
+func (p *Pool[T]) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+func TestQueuedJob(t *testing.T) {
+	tracer := mocktracer.New()
+	clock := tracing.NewFakeClock(time.Date(2015, 11, 1, 12, 0, 0, 0, time.UTC))
+	options := &tracing.QueueOptions{Clock: clock}
+	parent, ctx := tracing.StartSpanFromContext(context.Background(), tracer, "parent", nil)
+
+	queue := make(chan tracing.QueuedJob[string], 2)
+	require.NoError(t, tracing.Enqueue(ctx, queue, "first", options))
+	clock.Advance(time.Millisecond)
+	require.NoError(t, tracing.Enqueue(ctx, queue, "second", options))
+	clock.Advance(5 * time.Millisecond)
+	parent.End(nil)
+
+	<-queue
+	job := <-queue
+	assert.Equal(t, "second", job.Value)
+	span, jobCtx := job.Start("job")
+	current, err := tracing.GetSpanFromContext(jobCtx)
+	require.NoError(t, err)
+	assert.Equal(t, span, current)
+	span.End(nil)
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 2)
+	jobSpan := spans[1]
+	assert.Equal(t, "job", jobSpan.Name)
+	assert.Equal(t, spans[0], jobSpan.Parent)
+	assert.True(t, jobSpan.Options.Async)
+	enqueuedAt := time.Date(2015, 11, 1, 12, 0, 0, int(time.Millisecond), time.UTC)
+	assert.Equal(t, enqueuedAt, *jobSpan.Options.Timestamp)
+
+	depth, _ := jobSpan.Attribute(tracing.QueueDepthKey)
+	assert.Equal(t, int64(1), depth)
+	wait, _ := jobSpan.Attribute(tracing.QueueWaitKey)
+	assert.Equal(t, int64(5000), wait)
+
+	events := jobSpan.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, tracing.QueueEnqueuedEvent, events[0].Name)
+	assert.Equal(t, enqueuedAt, *events[0].Options.Timestamp)
+	assert.Equal(t, tracing.QueueDequeuedEvent, events[1].Name)
+	assert.Equal(t, enqueuedAt.Add(5*time.Millisecond), *events[1].Options.Timestamp)
+}
+
+func TestEnqueueCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := make(chan tracing.QueuedJob[int], 1)
+	require.NoError(t, tracing.Enqueue(ctx, queue, 1, nil))
+	cancel()
+
+	assert.Equal(t, context.Canceled, tracing.Enqueue(ctx, queue, 2, nil))
+	job := <-queue
+	assert.Equal(t, 1, job.Value)
+
+	tracer := mocktracer.New()
+	tracing.SetGlobalTracer(tracer)
+	defer tracing.SetGlobalTracer(nil)
+	span, jobCtx := job.Start("job")
+	span.End(nil)
+	assert.NoError(t, jobCtx.Err(), "the job does not inherit cancellation")
+	require.Len(t, tracer.FinishedSpans(), 1)
+	assert.Nil(t, tracer.FinishedSpans()[0].Parent)
+}
+
+func TestPool(t *testing.T) {
+	tracer := mocktracer.New()
+	parent, ctx := tracing.StartSpanFromContext(context.Background(), tracer, "parent", nil)
+
+	pool := tracing.NewPool("square", 2, 1, func(ctx context.Context, value int) error {
+		if value < 0 {
+			return errors.New("negative")
+		}
+		return nil
+	}, nil)
+	for _, value := range []int{1, -2, 3} {
+		require.NoError(t, pool.Submit(ctx, value))
+	}
+	pool.Close()
+	parent.End(nil)
+	assert.Equal(t, tracing.PoolClosedError, pool.Submit(ctx, 4))
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 4)
+	var errs []string
+	for _, span := range spans[1:] {
+		assert.Equal(t, "square", span.Name)
+		assert.Equal(t, spans[0], span.Parent)
+		if err := span.EndOptions().Error; err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	sort.Strings(errs)
+	assert.Equal(t, []string{"negative"}, errs)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rpctracing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/rpc"
+	"sync"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+type clientCodec struct {
+	codec   rpc.ClientCodec
+	options Options
+	peer    *tracing.Endpoint
+
+	mu    sync.Mutex
+	spans map[uint64]tracing.Span
+}
+
+// tracedArgs carries the context of the call from Call() to the codec.
+type tracedArgs struct {
+	ctx  context.Context
+	args interface{}
+}
+
+// NewClientCodec wraps the codec of a client connection, so that every call is recorded as a client span named
+// after the ServiceMethod, with the peer taken from the address of the connection, which may be nil. Calls made
+// with Call() or Go() are children of the span in their context, other calls start new traces.
+func NewClientCodec(codec rpc.ClientCodec, conn net.Conn, options *Options) rpc.ClientCodec {
+	c := &clientCodec{codec: codec, spans: make(map[uint64]tracing.Span)}
+	if options != nil {
+		c.options = *options
+	}
+	c.peer = peerFromConn(conn, c.options.PeerServiceName)
+	return c
+}
+
+// Call invokes the named function like rpc.Client.Call(), with the client span as a child of the span in the context.
+func Call(ctx context.Context, client *rpc.Client, serviceMethod string, args interface{}, reply interface{}) error {
+	call := <-Go(ctx, client, serviceMethod, args, reply, make(chan *rpc.Call, 1)).Done
+	return call.Error
+}
+
+// Go invokes the function asynchronously like rpc.Client.Go(), with the client span as a child of the span in the
+// context. The Args field of the returned call holds an internal wrapper of args.
+func Go(ctx context.Context, client *rpc.Client, serviceMethod string, args interface{}, reply interface{}, done chan *rpc.Call) *rpc.Call {
+	return client.Go(serviceMethod, &tracedArgs{ctx: ctx, args: args}, reply, done)
+}
+
+// WriteRequest implements WriteRequest() of rpc.ClientCodec
+func (c *clientCodec) WriteRequest(r *rpc.Request, body interface{}) error {
+	ctx := context.Background()
+	if t, ok := body.(*tracedArgs); ok {
+		ctx, body = t.ctx, t.args
+	}
+	options := &tracing.BeginOptions{Kind: tracing.SpanKindClient, Peer: c.peer}
+	var span tracing.Span
+	tracer := c.options.tracer()
+	if parent, err := tracing.GetSpanFromContext(ctx); err == nil {
+		span = parent.BeginChildSpan(r.ServiceMethod, options)
+		tracer = c.options.spanTracer(ctx)
+	} else {
+		span = tracer.BeginTrace(r.ServiceMethod, c.options.endpoint(ctx), options)
+	}
+
+	c.mu.Lock()
+	c.spans[r.Seq] = span
+	c.mu.Unlock()
+
+	request := *r
+	request.ServiceMethod = wrapServiceMethod(r.ServiceMethod, tracer.GetStringPickler().ToString(span.SpanID()))
+	err := c.codec.WriteRequest(&request, body)
+	if err != nil {
+		c.end(r.Seq, err)
+	}
+	return err
+}
+
+// ReadResponseHeader implements ReadResponseHeader() of rpc.ClientCodec
+func (c *clientCodec) ReadResponseHeader(r *rpc.Response) error {
+	err := c.codec.ReadResponseHeader(r)
+	if err == nil {
+		var callErr error
+		if r.Error != "" {
+			callErr = errors.New(r.Error)
+		}
+		c.end(r.Seq, callErr)
+	}
+	return err
+}
+
+// ReadResponseBody implements ReadResponseBody() of rpc.ClientCodec
+func (c *clientCodec) ReadResponseBody(body interface{}) error {
+	return c.codec.ReadResponseBody(body)
+}
+
+// Close implements Close() of rpc.ClientCodec. Spans of calls without a response are ended with rpc.ErrShutdown.
+func (c *clientCodec) Close() error {
+	err := c.codec.Close()
+	c.mu.Lock()
+	spans := c.spans
+	c.spans = make(map[uint64]tracing.Span)
+	c.mu.Unlock()
+	for _, span := range spans {
+		span.End(&tracing.EndOptions{Error: rpc.ErrShutdown})
+	}
+	return err
+}
+
+func (c *clientCodec) end(seq uint64, err error) {
+	c.mu.Lock()
+	span, ok := c.spans[seq]
+	delete(c.spans, seq)
+	c.mu.Unlock()
+	if ok {
+		span.End(&tracing.EndOptions{Error: err})
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package rpctracing instruments net/rpc clients and servers by wrapping their codecs.
+//
+// The net/rpc protocol has no headers, so the client codec carries the serialized span ID in an envelope
+// around the ServiceMethod of the request header, which the server codec removes before the request reaches
+// the rpc.Server. Both sides of a connection must therefore use the wrapping codecs. Requests from clients
+// without the envelope are still served, as new traces.
+package rpctracing
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+// envelopeSeparator separates the ServiceMethod from the serialized span ID in the request header.
+const envelopeSeparator = "|"
+
+// Options contains optional settings that can be passed to NewClientCodec() and NewServerCodec().
+type Options struct {
+	// Tracer creates the spans that have no parent span in the context. If nil, tracing.GlobalTracer() is used.
+	Tracer tracing.Tracer
+
+	// Endpoint describes the service using the codec. If nil, tracing.EndpointFromContext() of the call is used.
+	Endpoint *tracing.Endpoint
+
+	// PeerServiceName is the service name of the peer endpoint. The address of the peer is taken from the connection.
+	PeerServiceName string
+}
+
+func (o *Options) tracer() tracing.Tracer {
+	if o.Tracer == nil {
+		return tracing.GlobalTracer()
+	}
+	return o.Tracer
+}
+
+// spanTracer returns the tracer serializing the IDs of the spans in the context: the tracer stored in the
+// context, which created the spans, or the tracer of the options if the context stores none.
+func (o *Options) spanTracer(ctx context.Context) tracing.Tracer {
+	if tracer := tracing.TracerFromContext(ctx); tracer != tracing.GlobalTracer() {
+		return tracer
+	}
+	return o.tracer()
+}
+
+func (o *Options) endpoint(ctx context.Context) *tracing.Endpoint {
+	if o.Endpoint == nil {
+		return tracing.EndpointFromContext(ctx)
+	}
+	return o.Endpoint
+}
+
+func peerFromConn(conn net.Conn, serviceName string) *tracing.Endpoint {
+	if conn == nil {
+		return nil
+	}
+	peer, err := tracing.EndpointFromNetAddr(conn.RemoteAddr())
+	if err != nil {
+		return nil
+	}
+	peer.ServiceName = serviceName
+	return peer
+}
+
+func wrapServiceMethod(serviceMethod string, spanID string) string {
+	if spanID == "" {
+		return serviceMethod
+	}
+	return serviceMethod + envelopeSeparator + spanID
+}
+
+func unwrapServiceMethod(serviceMethod string) (string, string) {
+	if i := strings.Index(serviceMethod, envelopeSeparator); i >= 0 {
+		return serviceMethod[:i], serviceMethod[i+len(envelopeSeparator):]
+	}
+	return serviceMethod, ""
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rpctracing_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+	"github.com/uber-common/opentracing-go/rpctracing"
+)
+
+type Args struct {
+	A, B int
+}
+
+type Arith struct{}
+
+func (Arith) Multiply(args *Args, reply *int) error {
+	span, err := tracing.GetSpanFromContext(rpctracing.ContextFromArgs(args))
+	if err != nil {
+		return err
+	}
+	span.AddAttribute("a", int64(args.A))
+	span.AddEvent("multiplying", nil)
+	*reply = args.A * args.B
+	return nil
+}
+
+// Empty is a zero-size argument type, whose pointers are shared by all calls.
+type Empty struct{}
+
+func (Arith) Ping(args *Empty, reply *bool) error {
+	_, err := tracing.GetSpanFromContext(rpctracing.ContextFromArgs(args))
+	*reply = err == nil
+	return nil
+}
+
+func (Arith) Fail(args *Args, reply *int) error {
+	return errors.New("failure")
+}
+
+func newClient(t *testing.T, clientTracer, serverTracer tracing.Tracer) *rpc.Client {
+	server := rpc.NewServer()
+	require.NoError(t, server.Register(Arith{}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		server.ServeCodec(rpctracing.NewServerCodec(jsonrpc.NewServerCodec(conn), conn, &rpctracing.Options{
+			Tracer:   serverTracer,
+			Endpoint: &tracing.Endpoint{ServiceName: "arith"},
+		}))
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	client := rpc.NewClientWithCodec(rpctracing.NewClientCodec(jsonrpc.NewClientCodec(conn), conn, &rpctracing.Options{
+		Tracer:          clientTracer,
+		PeerServiceName: "arith",
+	}))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestCall(t *testing.T) {
+	clientTracer, serverTracer := mocktracer.New(), mocktracer.New()
+	client := newClient(t, clientTracer, serverTracer)
+
+	parent, ctx := tracing.StartSpanFromContext(context.Background(), clientTracer, "parent", nil)
+	var reply int
+	require.NoError(t, rpctracing.Call(ctx, client, "Arith.Multiply", &Args{6, 7}, &reply))
+	assert.Equal(t, 42, reply)
+	parent.End(nil)
+
+	clientSpans := clientTracer.FinishedSpans()
+	require.Len(t, clientSpans, 2)
+	clientSpan := clientSpans[1]
+	assert.Equal(t, "Arith.Multiply", clientSpan.Name)
+	assert.Equal(t, clientSpans[0], clientSpan.Parent)
+	assert.Equal(t, tracing.SpanKindClient, clientSpan.Kind)
+	require.NotNil(t, clientSpan.Options.Peer)
+	assert.Equal(t, "arith", clientSpan.Options.Peer.ServiceName)
+	assert.EqualValues(t, 127<<24|1, clientSpan.Options.Peer.IPv4)
+	assert.NoError(t, clientSpan.EndOptions().Error)
+
+	serverSpans := serverTracer.FinishedSpans()
+	require.Len(t, serverSpans, 1)
+	serverSpan := serverSpans[0]
+	assert.Equal(t, "Arith.Multiply", serverSpan.Name)
+	assert.Equal(t, "arith", serverSpan.Service.ServiceName)
+	assert.Equal(t, tracing.SpanKindServer, serverSpan.Kind)
+	assert.Equal(t, clientSpan.SpanID(), serverSpan.SpanID(), "the server joins the client span")
+	require.NotNil(t, serverSpan.Options.Peer)
+	assert.EqualValues(t, 127<<24|1, serverSpan.Options.Peer.IPv4)
+	assert.Equal(t, "multiplying", serverSpan.Events()[0].Name)
+}
+
+func TestCallWithParentWithoutTracer(t *testing.T) {
+	clientTracer, serverTracer := mocktracer.New(), mocktracer.New()
+	client := newClient(t, clientTracer, serverTracer)
+
+	// the context stores the parent span, but not its tracer
+	ctx := tracing.ContextWithSpan(context.Background(), clientTracer.BeginTrace("parent", nil, nil))
+	var reply int
+	require.NoError(t, rpctracing.Call(ctx, client, "Arith.Multiply", &Args{6, 7}, &reply))
+
+	clientSpans := clientTracer.FinishedSpans()
+	require.Len(t, clientSpans, 1)
+	serverSpans := serverTracer.FinishedSpans()
+	require.Len(t, serverSpans, 1)
+	assert.Equal(t, clientSpans[0].SpanID(), serverSpans[0].SpanID(), "the ID is serialized by the client tracer")
+}
+
+func TestCallWithoutContext(t *testing.T) {
+	clientTracer, serverTracer := mocktracer.New(), mocktracer.New()
+	client := newClient(t, clientTracer, serverTracer)
+
+	var reply int
+	err := client.Call("Arith.Fail", &Args{1, 2}, &reply)
+	assert.EqualError(t, err, "failure")
+	err = client.Call("Arith.Missing", &Args{1, 2}, &reply)
+	assert.Error(t, err)
+
+	clientSpans := clientTracer.FinishedSpans()
+	require.Len(t, clientSpans, 2)
+	assert.Nil(t, clientSpans[0].Parent)
+	assert.EqualError(t, clientSpans[0].EndOptions().Error, "failure")
+	assert.Error(t, clientSpans[1].EndOptions().Error)
+
+	serverSpans := serverTracer.FinishedSpans()
+	require.Len(t, serverSpans, 2)
+	assert.Equal(t, clientSpans[0].SpanID(), serverSpans[0].SpanID())
+	assert.EqualError(t, serverSpans[0].EndOptions().Error, "failure")
+	assert.Equal(t, "Arith.Missing", serverSpans[1].Name)
+	assert.Error(t, serverSpans[1].EndOptions().Error)
+
+	assert.Equal(t, context.Background(), rpctracing.ContextFromArgs(&Args{}))
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	clientTracer, serverTracer := mocktracer.New(), mocktracer.New()
+	client := newClient(t, clientTracer, serverTracer)
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var reply int
+			assert.NoError(t, client.Call("Arith.Multiply", &Args{i, 2}, &reply))
+			assert.Equal(t, 2*i, reply)
+			var traced bool
+			assert.NoError(t, client.Call("Arith.Ping", &Empty{}, &traced))
+			assert.False(t, traced, "zero-size args cannot be told apart")
+		}(i)
+	}
+	wg.Wait()
+
+	serverSpans := serverTracer.FinishedSpans()
+	require.Len(t, serverSpans, 2*n)
+	seen := make(map[int64]bool)
+	for _, s := range serverSpans {
+		assert.True(t, s.Ended())
+		if s.Name != "Arith.Multiply" {
+			continue
+		}
+		a, ok := s.Attribute("a")
+		require.True(t, ok, "every call sees its own span")
+		seen[a.(int64)] = true
+	}
+	assert.Len(t, seen, n)
+}
+
+// stubServerCodec serves a single request and never receives the response.
+type stubServerCodec struct{ closed bool }
+
+func (c *stubServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	r.ServiceMethod, r.Seq = "Arith.Multiply", 1
+	return nil
+}
+
+func (c *stubServerCodec) ReadRequestBody(body interface{}) error {
+	return nil
+}
+
+func (c *stubServerCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	return nil
+}
+
+func (c *stubServerCodec) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestServerCodecClose(t *testing.T) {
+	tracer := mocktracer.New()
+	stub := &stubServerCodec{}
+	codec := rpctracing.NewServerCodec(stub, nil, &rpctracing.Options{Tracer: tracer})
+
+	var r rpc.Request
+	require.NoError(t, codec.ReadRequestHeader(&r))
+	args := &Args{1, 2}
+	require.NoError(t, codec.ReadRequestBody(args))
+	_, err := tracing.GetSpanFromContext(rpctracing.ContextFromArgs(args))
+	require.NoError(t, err)
+
+	require.NoError(t, codec.Close())
+	assert.True(t, stub.closed)
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, rpc.ErrShutdown, spans[0].EndOptions().Error)
+	assert.Equal(t, context.Background(), rpctracing.ContextFromArgs(args))
+}
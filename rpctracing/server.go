@@ -0,0 +1,167 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rpctracing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/rpc"
+	"reflect"
+	"sync"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+type serverCodec struct {
+	codec   rpc.ServerCodec
+	options Options
+	peer    *tracing.Endpoint
+
+	mu    sync.Mutex
+	calls map[uint64]*serverCall
+	// current is the call whose header was read last; net/rpc reads the body right after the header.
+	current *serverCall
+}
+
+type serverCall struct {
+	ctx  context.Context
+	span tracing.Span
+	args interface{}
+}
+
+// activeCalls maps the args of the calls being served to the calls, for ContextFromArgs(). Only args with
+// a unique address are stored, see registerArgs().
+var activeCalls sync.Map
+
+// NewServerCodec wraps the codec of a server connection, so that every request is recorded as a server span
+// named after the ServiceMethod, with the peer taken from the address of the connection, which may be nil.
+// The span joins the trace of the client if the request carries a span ID, and it ends before the response
+// is written. The methods of the service can retrieve the context with the span with ContextFromArgs().
+func NewServerCodec(codec rpc.ServerCodec, conn net.Conn, options *Options) rpc.ServerCodec {
+	c := &serverCodec{codec: codec, calls: make(map[uint64]*serverCall)}
+	if options != nil {
+		c.options = *options
+	}
+	c.peer = peerFromConn(conn, c.options.PeerServiceName)
+	return c
+}
+
+// ContextFromArgs returns a context storing the server span, the tracer and the endpoint of the call being
+// served with the given args. The args must be the pointer received by the method of the service, so the
+// argument type of the method must be a pointer to a type of non-zero size: pointers to zero-size types,
+// such as struct{}, are shared by all calls. If the args are not recognized, context.Background() is returned.
+func ContextFromArgs(args interface{}) context.Context {
+	if call, ok := activeCalls.Load(args); ok {
+		return call.(*serverCall).ctx
+	}
+	return context.Background()
+}
+
+// ReadRequestHeader implements ReadRequestHeader() of rpc.ServerCodec
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	if err := c.codec.ReadRequestHeader(r); err != nil {
+		return err
+	}
+	serviceMethod, header := unwrapServiceMethod(r.ServiceMethod)
+	r.ServiceMethod = serviceMethod
+
+	tracer := c.options.tracer()
+	endpoint := c.options.endpoint(context.Background())
+	options := &tracing.BeginOptions{Kind: tracing.SpanKindServer, Peer: c.peer}
+	span, err := tracing.GetSpanFromHeader(header, tracer, serviceMethod, endpoint, options)
+	if err != nil {
+		span = tracer.BeginTrace(serviceMethod, endpoint, options)
+	}
+	ctx := tracing.ContextWithEndpoint(tracing.ContextWithTracer(context.Background(), tracer), endpoint)
+	ctx = tracing.ContextWithSpan(ctx, span)
+	call := &serverCall{ctx: ctx, span: span}
+
+	c.mu.Lock()
+	c.calls[r.Seq] = call
+	c.current = call
+	c.mu.Unlock()
+	return nil
+}
+
+// ReadRequestBody implements ReadRequestBody() of rpc.ServerCodec
+func (c *serverCodec) ReadRequestBody(body interface{}) error {
+	err := c.codec.ReadRequestBody(body)
+
+	c.mu.Lock()
+	call := c.current
+	c.current = nil
+	c.mu.Unlock()
+	if call != nil && err == nil && registerArgs(body, call) {
+		call.args = body
+	}
+	return err
+}
+
+// registerArgs stores the call for ContextFromArgs() and reports whether it did. The args are stored only if
+// they are a pointer to a type of non-zero size, because the pointers to zero-size values are not unique.
+func registerArgs(args interface{}, call *serverCall) bool {
+	v := reflect.ValueOf(args)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Type().Elem().Size() == 0 {
+		return false
+	}
+	activeCalls.Store(args, call)
+	return true
+}
+
+// WriteResponse implements WriteResponse() of rpc.ServerCodec. The span is ended before the response is
+// written, so that it is reported by the time the client receives the response.
+func (c *serverCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	c.mu.Lock()
+	call, ok := c.calls[r.Seq]
+	delete(c.calls, r.Seq)
+	c.mu.Unlock()
+
+	if ok {
+		var callErr error
+		if r.Error != "" {
+			callErr = errors.New(r.Error)
+		}
+		call.end(callErr)
+	}
+	return c.codec.WriteResponse(r, body)
+}
+
+// Close implements Close() of rpc.ServerCodec. Spans of calls without a response are ended with rpc.ErrShutdown.
+func (c *serverCodec) Close() error {
+	err := c.codec.Close()
+	c.mu.Lock()
+	calls := c.calls
+	c.calls = make(map[uint64]*serverCall)
+	c.current = nil
+	c.mu.Unlock()
+	for _, call := range calls {
+		call.end(rpc.ErrShutdown)
+	}
+	return err
+}
+
+func (call *serverCall) end(err error) {
+	if call.args != nil {
+		activeCalls.Delete(call.args)
+	}
+	call.span.End(&tracing.EndOptions{Error: err})
+}
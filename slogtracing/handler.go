@@ -0,0 +1,147 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package slogtracing correlates log/slog records with tracing spans.
+package slogtracing
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+// Names of the log attributes that carry the IDs of the current span.
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// Options contains optional settings that can be passed to NewHandler().
+type Options struct {
+	// EventLevel enables mirroring of log records at or above the level as events on the current span.
+	// If nil, records are not mirrored.
+	EventLevel slog.Leveler
+}
+
+type handler struct {
+	// next is the handler the records are passed to, with all the groups and attributes of the handler.
+	next slog.Handler
+	// root is the next handler before the first group was opened, and groups holds the groups and the
+	// attributes added since, so that the IDs can be added at the top level of the records.
+	root    slog.Handler
+	groups  []groupOrAttrs
+	options Options
+}
+
+// groupOrAttrs is a group opened with WithGroup(), or the attributes added with WithAttrs() if group is empty.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewHandler returns a slog.Handler that adds the IDs of the current span in the context to every record before
+// passing it to the next handler. Spans with a tracing.ZipkinSpanID get the TraceIDKey and SpanIDKey attributes
+// in hexadecimal, other spans get only the SpanIDKey attribute with the string form of the span ID. The IDs are
+// added at the top level of the records, outside of the groups opened with WithGroup(), so that they can be
+// found under fixed keys. Records without a span in the context are passed unchanged. Optionally the records
+// are also added to the span as events.
+func NewHandler(next slog.Handler, options *Options) slog.Handler {
+	h := &handler{next: next, root: next}
+	if options != nil {
+		h.options = *options
+	}
+	return h
+}
+
+// Enabled implements Enabled() of slog.Handler
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements Handle() of slog.Handler
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	span, err := tracing.GetSpanFromContext(ctx)
+	if err != nil {
+		return h.next.Handle(ctx, record)
+	}
+	if h.options.EventLevel != nil && record.Level >= h.options.EventLevel.Level() {
+		var options *tracing.EventOptions
+		if !record.Time.IsZero() {
+			ts := tracing.Timestamp(record.Time)
+			options = &tracing.EventOptions{TimeOption: tracing.TimeOption{Timestamp: &ts}}
+		}
+		span.AddEvent(record.Message, options)
+	}
+
+	var ids []slog.Attr
+	if id, ok := span.SpanID().(tracing.ZipkinSpanID); ok {
+		ids = []slog.Attr{slog.String(TraceIDKey, formatID(id.TraceID())), slog.String(SpanIDKey, formatID(id.ID()))}
+	} else {
+		ids = []slog.Attr{slog.String(SpanIDKey, span.SpanID().String())}
+	}
+	if len(h.groups) == 0 {
+		record = record.Clone()
+		record.AddAttrs(ids...)
+		return h.next.Handle(ctx, record)
+	}
+
+	// the attributes of the record belong to the innermost group, so the IDs are added to the handler instead
+	next := h.root.WithAttrs(ids)
+	for _, g := range h.groups {
+		if g.group != "" {
+			next = next.WithGroup(g.group)
+		} else {
+			next = next.WithAttrs(g.attrs)
+		}
+	}
+	return next.Handle(ctx, record)
+}
+
+// WithAttrs implements WithAttrs() of slog.Handler
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	if len(h.groups) == 0 {
+		next := h.next.WithAttrs(attrs)
+		return &handler{next: next, root: next, options: h.options}
+	}
+	return h.with(groupOrAttrs{attrs: attrs}, h.next.WithAttrs(attrs))
+}
+
+// WithGroup implements WithGroup() of slog.Handler
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name}, h.next.WithGroup(name))
+}
+
+func (h *handler) with(g groupOrAttrs, next slog.Handler) *handler {
+	groups := make([]groupOrAttrs, len(h.groups), len(h.groups)+1)
+	copy(groups, h.groups)
+	return &handler{next: next, root: h.root, groups: append(groups, g), options: h.options}
+}
+
+func formatID(id int64) string {
+	return strconv.FormatUint(uint64(id), 16)
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package slogtracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+	"github.com/uber-common/opentracing-go/slogtracing"
+)
+
+func newLogger(options *slogtracing.Options) (*slog.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	return slog.New(slogtracing.NewHandler(slog.NewJSONHandler(buf, nil), options)), buf
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	buf.Reset()
+	return record
+}
+
+func TestHandlerZipkinIDs(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.JoinTrace("server", nil, tracer.CreateSpanID(0x1234, -1, 0, 1), nil)
+	ctx := tracing.ContextWithSpan(context.Background(), span)
+	logger, buf := newLogger(nil)
+
+	logger.InfoContext(ctx, "hello", "key", "value")
+	record := decode(t, buf)
+	assert.Equal(t, "hello", record["msg"])
+	assert.Equal(t, "value", record["key"])
+	assert.Equal(t, "1234", record[slogtracing.TraceIDKey])
+	assert.Equal(t, "ffffffffffffffff", record[slogtracing.SpanIDKey])
+	assert.Empty(t, tracer.Spans()[0].Events())
+
+	logger.Info("no context")
+	record = decode(t, buf)
+	assert.NotContains(t, record, slogtracing.TraceIDKey)
+	assert.NotContains(t, record, slogtracing.SpanIDKey)
+
+	logger.With("a", 1).WithGroup("g").With("b", 2).WithGroup("h").InfoContext(ctx, "grouped", "c", 3)
+	record = decode(t, buf)
+	assert.EqualValues(t, 1, record["a"])
+	assert.Equal(t, "1234", record[slogtracing.TraceIDKey], "the IDs are added at the top level")
+	assert.Equal(t, "ffffffffffffffff", record[slogtracing.SpanIDKey])
+	g := record["g"].(map[string]interface{})
+	assert.NotContains(t, g, slogtracing.TraceIDKey)
+	assert.EqualValues(t, 2, g["b"])
+	assert.EqualValues(t, 3, g["h"].(map[string]interface{})["c"])
+
+	logger.WithGroup("g").Info("no context", "c", 3)
+	record = decode(t, buf)
+	assert.NotContains(t, record, slogtracing.TraceIDKey)
+	assert.EqualValues(t, 3, record["g"].(map[string]interface{})["c"])
+}
+
+func TestHandlerOpaqueIDs(t *testing.T) {
+	// the noop span ID implements ZipkinSpanID, so it is hidden behind the plain SpanID interface
+	span := opaqueSpan{tracing.NewNoopTracer().BeginTrace("root", nil, nil)}
+	ctx := tracing.ContextWithSpan(context.Background(), span)
+	logger, buf := newLogger(nil)
+
+	logger.InfoContext(ctx, "hello")
+	record := decode(t, buf)
+	assert.NotContains(t, record, slogtracing.TraceIDKey)
+	assert.Equal(t, "tracing-disabled", record[slogtracing.SpanIDKey])
+}
+
+type opaqueSpan struct {
+	tracing.Span
+}
+
+type opaqueSpanID struct {
+	id tracing.SpanID
+}
+
+func (s opaqueSpan) SpanID() tracing.SpanID {
+	return opaqueSpanID{s.Span.SpanID()}
+}
+
+func (id opaqueSpanID) String() string {
+	return id.id.String()
+}
+
+func TestHandlerEvents(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.BeginTrace("root", nil, nil)
+	ctx := tracing.ContextWithSpan(context.Background(), span)
+	logger, buf := newLogger(&slogtracing.Options{EventLevel: slog.LevelWarn})
+
+	logger.InfoContext(ctx, "info")
+	logger.WarnContext(ctx, "warning")
+	logger.ErrorContext(ctx, "error")
+	assert.NotZero(t, buf.Len())
+
+	events := tracer.Spans()[0].Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "warning", events[0].Name)
+	assert.Equal(t, "error", events[1].Name)
+	assert.NotNil(t, events[0].Options.Timestamp)
+}
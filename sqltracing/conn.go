@@ -0,0 +1,400 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sqltracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+type tracedConn struct {
+	conn driver.Conn
+	peer *tracing.Endpoint
+}
+
+// Prepare implements Prepare() of driver.Conn
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements PrepareContext() of driver.ConnPrepareContext
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	span := startSpan(ctx, PrepareSpan, c.peer, query, nil)
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{stmt: stmt, conn: c, query: query}, nil
+}
+
+// Close implements Close() of driver.Conn
+func (c *tracedConn) Close() error {
+	return c.conn.Close()
+}
+
+// Begin implements Begin() of driver.Conn
+func (c *tracedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements BeginTx() of driver.ConnBeginTx
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	span := startSpan(ctx, BeginSpan, c.peer, "", nil)
+	var tx driver.Tx
+	var err error
+	if b, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		err = errors.New("sqltracing: driver does not support non-default transaction options")
+	} else {
+		tx, err = c.conn.Begin()
+	}
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedTx{tx: tx, ctx: ctx, peer: c.peer}, nil
+}
+
+// QueryContext implements QueryContext() of driver.QueryerContext. Drivers implementing only the legacy
+// driver.Queryer are called through it, so that database/sql does not fall back to a prepared statement.
+// The span is started once the driver has run the query, so that no span is reported when the driver
+// returns driver.ErrSkip and database/sql falls back to a prepared statement.
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := c.conn.(driver.QueryerContext); ok {
+		rows, err = q.QueryContext(ctx, query, args)
+	} else if q, ok := c.conn.(driver.Queryer); ok {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = q.Query(query, values)
+		}
+	} else {
+		return nil, driver.ErrSkip
+	}
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	return wrapRows(startSpan(ctx, QuerySpan, c.peer, query, &start), rows, err)
+}
+
+// ExecContext implements ExecContext() of driver.ExecerContext. Drivers implementing only the legacy
+// driver.Execer are called through it, so that database/sql does not fall back to a prepared statement.
+// Like in QueryContext(), no span is reported when the driver returns driver.ErrSkip.
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if e, ok := c.conn.(driver.ExecerContext); ok {
+		result, err = e.ExecContext(ctx, query, args)
+	} else if e, ok := c.conn.(driver.Execer); ok {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = e.Exec(query, values)
+		}
+	} else {
+		return nil, driver.ErrSkip
+	}
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	recordResult(startSpan(ctx, ExecSpan, c.peer, query, &start), result, err)
+	return result, err
+}
+
+// Ping implements Ping() of driver.Pinger
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession implements ResetSession() of driver.SessionResetter
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements IsValid() of driver.Validator
+func (c *tracedConn) IsValid() bool {
+	if v, ok := c.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue implements CheckNamedValue() of driver.NamedValueChecker
+func (c *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func recordResult(span tracing.Span, result driver.Result, err error) {
+	if span == nil {
+		return
+	}
+	if err == nil {
+		if n, rowsErr := result.RowsAffected(); rowsErr == nil {
+			span.AddAttribute(RowsAffectedKey, n)
+		}
+	}
+	endSpan(span, err)
+}
+
+// -----
+
+type tracedStmt struct {
+	stmt  driver.Stmt
+	conn  *tracedConn
+	query string
+}
+
+// Close implements Close() of driver.Stmt
+func (s *tracedStmt) Close() error {
+	return s.stmt.Close()
+}
+
+// NumInput implements NumInput() of driver.Stmt
+func (s *tracedStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+// Exec implements Exec() of driver.Stmt
+func (s *tracedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.stmt.Exec(args)
+}
+
+// Query implements Query() of driver.Stmt
+func (s *tracedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.stmt.Query(args)
+}
+
+// ExecContext implements ExecContext() of driver.StmtExecContext. No span is reported when the driver
+// returns driver.ErrSkip.
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if e, ok := s.stmt.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else if values, convErr := namedValuesToValues(args); convErr != nil {
+		err = convErr
+	} else {
+		result, err = s.stmt.Exec(values)
+	}
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	recordResult(startSpan(ctx, ExecSpan, s.conn.peer, s.query, &start), result, err)
+	return result, err
+}
+
+// QueryContext implements QueryContext() of driver.StmtQueryContext. No span is reported when the driver
+// returns driver.ErrSkip.
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else if values, convErr := namedValuesToValues(args); convErr != nil {
+		err = convErr
+	} else {
+		rows, err = s.stmt.Query(values)
+	}
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	return wrapRows(startSpan(ctx, QuerySpan, s.conn.peer, s.query, &start), rows, err)
+}
+
+// CheckNamedValue implements CheckNamedValue() of driver.NamedValueChecker
+func (s *tracedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+// ColumnConverter implements ColumnConverter() of driver.ColumnConverter. Statements of drivers that do
+// not implement it use driver.DefaultParameterConverter, the same as database/sql does.
+func (s *tracedStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if cc, ok := s.stmt.(driver.ColumnConverter); ok {
+		return cc.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+func namedValuesToValues(named []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(named))
+	for i, nv := range named {
+		if nv.Name != "" {
+			return nil, errors.New("sqltracing: driver does not support named parameters")
+		}
+		values[i] = nv.Value
+	}
+	return values, nil
+}
+
+// -----
+
+type tracedTx struct {
+	tx   driver.Tx
+	ctx  context.Context
+	peer *tracing.Endpoint
+}
+
+// Commit implements Commit() of driver.Tx
+func (t *tracedTx) Commit() error {
+	span := startSpan(t.ctx, CommitSpan, t.peer, "", nil)
+	err := t.tx.Commit()
+	endSpan(span, err)
+	return err
+}
+
+// Rollback implements Rollback() of driver.Tx
+func (t *tracedTx) Rollback() error {
+	span := startSpan(t.ctx, RollbackSpan, t.peer, "", nil)
+	err := t.tx.Rollback()
+	endSpan(span, err)
+	return err
+}
+
+// -----
+
+// tracedRows counts the returned rows and ends the query span when the rows are closed.
+type tracedRows struct {
+	rows  driver.Rows
+	span  tracing.Span
+	count int64
+	err   error
+}
+
+func wrapRows(span tracing.Span, rows driver.Rows, err error) (driver.Rows, error) {
+	if err != nil || span == nil {
+		endSpan(span, err)
+		return rows, err
+	}
+	return &tracedRows{rows: rows, span: span}, nil
+}
+
+// Columns implements Columns() of driver.Rows
+func (r *tracedRows) Columns() []string {
+	return r.rows.Columns()
+}
+
+// Close implements Close() of driver.Rows
+func (r *tracedRows) Close() error {
+	err := r.rows.Close()
+	r.span.AddAttribute(RowsKey, r.count)
+	if r.err == nil {
+		r.err = err
+	}
+	r.span.End(&tracing.EndOptions{Error: r.err})
+	return err
+}
+
+// Next implements Next() of driver.Rows
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.rows.Next(dest)
+	if err == nil {
+		r.count++
+	} else if err != io.EOF {
+		r.err = err
+	}
+	return err
+}
+
+// HasNextResultSet implements HasNextResultSet() of driver.RowsNextResultSet
+func (r *tracedRows) HasNextResultSet() bool {
+	if n, ok := r.rows.(driver.RowsNextResultSet); ok {
+		return n.HasNextResultSet()
+	}
+	return false
+}
+
+// NextResultSet implements NextResultSet() of driver.RowsNextResultSet
+func (r *tracedRows) NextResultSet() error {
+	if n, ok := r.rows.(driver.RowsNextResultSet); ok {
+		return n.NextResultSet()
+	}
+	return io.EOF
+}
+
+// ColumnTypeScanType implements ColumnTypeScanType() of driver.RowsColumnTypeScanType
+func (r *tracedRows) ColumnTypeScanType(index int) reflect.Type {
+	if c, ok := r.rows.(driver.RowsColumnTypeScanType); ok {
+		return c.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+// ColumnTypeDatabaseTypeName implements ColumnTypeDatabaseTypeName() of driver.RowsColumnTypeDatabaseTypeName
+func (r *tracedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if c, ok := r.rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return c.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+// ColumnTypeLength implements ColumnTypeLength() of driver.RowsColumnTypeLength
+func (r *tracedRows) ColumnTypeLength(index int) (int64, bool) {
+	if c, ok := r.rows.(driver.RowsColumnTypeLength); ok {
+		return c.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+// ColumnTypeNullable implements ColumnTypeNullable() of driver.RowsColumnTypeNullable
+func (r *tracedRows) ColumnTypeNullable(index int) (bool, bool) {
+	if c, ok := r.rows.(driver.RowsColumnTypeNullable); ok {
+		return c.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+// ColumnTypePrecisionScale implements ColumnTypePrecisionScale() of driver.RowsColumnTypePrecisionScale
+func (r *tracedRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if c, ok := r.rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return c.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
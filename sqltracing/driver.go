@@ -0,0 +1,118 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package sqltracing traces database/sql queries by wrapping the database driver.
+package sqltracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+// Names of the attributes recorded on database spans.
+const (
+	StatementKey    = "db.statement"
+	RowsAffectedKey = "db.rows_affected"
+	RowsKey         = "db.rows"
+)
+
+// Names of the spans created for database operations.
+const (
+	QuerySpan    = "sql.query"
+	ExecSpan     = "sql.exec"
+	PrepareSpan  = "sql.prepare"
+	BeginSpan    = "sql.begin"
+	CommitSpan   = "sql.commit"
+	RollbackSpan = "sql.rollback"
+)
+
+// Options contains optional settings that can be passed to Wrap() and Register().
+type Options struct {
+	// Peer returns the endpoint of the database server from the data source name passed to sql.Open().
+	// If nil, PeerFromDSN is used.
+	Peer func(dsn string) *tracing.Endpoint
+}
+
+// Register wraps the driver with Wrap() and registers it with database/sql under the given name,
+// which can then be passed to sql.Open().
+func Register(name string, d driver.Driver, options *Options) {
+	sql.Register(name, Wrap(d, options))
+}
+
+// Wrap returns a driver that traces the database operations performed through the given driver. Queries,
+// statement executions, statement preparation, and the beginning, commit and rollback of transactions are
+// recorded as client spans, which are children of the current span in the context passed to the
+// database/sql methods. Operations without a span in the context are not traced. The spans record the
+// normalized statement, the number of affected or returned rows, the errors, and the database server
+// as the peer. Query spans end when the returned rows are closed.
+func Wrap(d driver.Driver, options *Options) driver.Driver {
+	w := &tracedDriver{driver: d}
+	if options != nil {
+		w.options = *options
+	}
+	if w.options.Peer == nil {
+		w.options.Peer = PeerFromDSN
+	}
+	return w
+}
+
+type tracedDriver struct {
+	driver  driver.Driver
+	options Options
+}
+
+// Open implements Open() of driver.Driver
+func (d *tracedDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{conn: conn, peer: d.options.Peer(dsn)}, nil
+}
+
+// startSpan starts a child span of the current span in the context, or returns nil if there is none.
+// If start is not nil, the span is started at that time rather than now.
+func startSpan(ctx context.Context, name string, peer *tracing.Endpoint, query string, start *time.Time) tracing.Span {
+	parent, err := tracing.GetSpanFromContext(ctx)
+	if err != nil {
+		return nil
+	}
+	span := parent.BeginChildSpan(name, &tracing.BeginOptions{
+		TimeOption: tracing.TimeOption{Timestamp: start},
+		Kind:       tracing.SpanKindClient,
+		Peer:       peer,
+	})
+	if query != "" {
+		span.AddAttribute(StatementKey, NormalizeQuery(query))
+	}
+	return span
+}
+
+// endSpan ends the span unless it is nil.
+func endSpan(span tracing.Span, err error) {
+	if span == nil {
+		return
+	}
+	span.End(&tracing.EndOptions{Error: err})
+}
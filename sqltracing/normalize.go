@@ -0,0 +1,115 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sqltracing
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/uber-common/opentracing-go"
+)
+
+// PeerFromDSN returns the endpoint of the database server found in the data source name, or nil if there is none.
+// It understands URL DSNs ("postgres://user@db.local:5432/name"), the MySQL format ("user@tcp(db.local:3306)/name")
+// and key/value DSNs ("host=db.local port=5432"). The service name of the endpoint is the host name.
+func PeerFromDSN(dsn string) *tracing.Endpoint {
+	var host, port string
+	if u, err := url.Parse(dsn); err == nil && u.Host != "" {
+		host, port = u.Hostname(), u.Port()
+	} else if i := strings.Index(dsn, "tcp("); i >= 0 {
+		addr := dsn[i+len("tcp("):]
+		if j := strings.IndexByte(addr, ')'); j >= 0 {
+			addr = addr[:j]
+		}
+		if host, port, err = net.SplitHostPort(addr); err != nil {
+			host = addr
+		}
+	} else {
+		for _, field := range strings.Fields(dsn) {
+			if v, ok := strings.CutPrefix(field, "host="); ok {
+				host = v
+			} else if v, ok := strings.CutPrefix(field, "port="); ok {
+				port = v
+			}
+		}
+	}
+	if host == "" {
+		return nil
+	}
+	addr := host
+	if port != "" {
+		addr = net.JoinHostPort(host, port)
+	}
+	peer, err := tracing.EndpointFromAddr(host, addr)
+	if err != nil {
+		return nil
+	}
+	return peer
+}
+
+// NormalizeQuery prepares a SQL statement for recording on a span: string and numeric literals are replaced
+// with "?" placeholders, so that the statement does not leak data and has limited cardinality, and runs of
+// whitespace are collapsed into a single space.
+func NormalizeQuery(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	space := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = b.Len() > 0
+			continue
+		case c == '\'':
+			// skip the string literal, '' is an escaped quote
+			for i++; i < len(query); i++ {
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			c = '?'
+		case isDigit(c) && (i == 0 || !isIdentifier(query[i-1])):
+			for i+1 < len(query) && (isDigit(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+			c = '?'
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifier(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
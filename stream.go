@@ -0,0 +1,182 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import (
+	"sync"
+	"time"
+)
+
+// Names of the spans and attributes recorded by Stream.
+const (
+	// StreamHeartbeatSpanName is the name of the spans summarizing the traffic of a stream in an interval.
+	StreamHeartbeatSpanName = "stream.heartbeat"
+
+	// StreamMessagesKey is the attribute holding the number of messages, on heartbeat spans for the interval
+	// and on the stream span for its whole life.
+	StreamMessagesKey = "stream.messages"
+
+	// StreamBytesKey is the attribute holding the number of message bytes, on heartbeat spans for the interval
+	// and on the stream span for its whole life.
+	StreamBytesKey = "stream.bytes"
+
+	// StreamMessageSizeKey is the attribute holding the size of the message in bytes on message spans.
+	StreamMessageSizeKey = "stream.message_size"
+)
+
+// StreamOptions contains optional settings that can be passed to NewStream().
+type StreamOptions struct {
+	// HeartbeatInterval is the period of heartbeat spans. If zero, heartbeats are only sent by calling Heartbeat().
+	HeartbeatInterval time.Duration
+
+	// Clock provides the start times and durations of heartbeat spans. If nil, the system clock is used.
+	Clock Clock
+}
+
+// Stream traces a long-lived connection of a stream-oriented protocol, such as a WebSocket or an HTTP/2 stream.
+// The connection is represented by a span that is typically ended long after the messages exchanged over it
+// were processed, so the messages are traced as Async child spans that do not extend the connection span,
+// and the traffic is summarized in heartbeat spans reported while the connection is open.
+// Stream implements Span, delegating to the connection span.
+type Stream struct {
+	Span
+	clock Clock
+	stop  chan struct{}
+	done  chan struct{}
+
+	mu            sync.Mutex
+	ended         bool
+	lastHeartbeat time.Time
+	messages      int64
+	bytes         int64
+	totalMessages int64
+	totalBytes    int64
+}
+
+// NewStream wraps the span representing a connection. If options specify HeartbeatInterval, a goroutine
+// calls Heartbeat() periodically until the stream is ended.
+func NewStream(span Span, options *StreamOptions) *Stream {
+	opts := StreamOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Clock == nil {
+		opts.Clock = NewSystemClock()
+	}
+	s := &Stream{Span: span, clock: opts.Clock, lastHeartbeat: opts.Clock.Now()}
+	if opts.HeartbeatInterval > 0 {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.heartbeats(opts.HeartbeatInterval)
+	}
+	return s
+}
+
+func (s *Stream) heartbeats(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Heartbeat()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// BeginMessage starts an Async child span of the connection span for processing a message of the given size
+// in bytes, and counts the message in the stream statistics. The size is recorded as the StreamMessageSizeKey
+// attribute. The Async flag in options is always set.
+func (s *Stream) BeginMessage(name string, size int, options *BeginOptions) Span {
+	s.AddMessage(size)
+	opts := BeginOptions{}
+	if options != nil {
+		opts = *options
+	}
+	opts.Async = true
+	span := s.Span.BeginChildSpan(name, &opts)
+	span.AddAttribute(StreamMessageSizeKey, int64(size))
+	return span
+}
+
+// AddMessage counts a message of the given size in bytes in the stream statistics without tracing it.
+func (s *Stream) AddMessage(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages++
+	s.bytes += int64(size)
+}
+
+// Heartbeat reports the traffic since the previous heartbeat, or since the stream was created, as an Async
+// child span of the connection span named StreamHeartbeatSpanName. The heartbeat span covers the interval
+// and holds the StreamMessagesKey and StreamBytesKey attributes. It is ended immediately, so that the
+// reporter receives it while the connection is still open. Heartbeat does nothing after the stream is ended.
+func (s *Stream) Heartbeat() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	now := s.clock.Now()
+	start := Timestamp(s.lastHeartbeat)
+	duration := now.Sub(s.lastHeartbeat).Truncate(time.Microsecond)
+
+	span := s.Span.BeginChildSpan(StreamHeartbeatSpanName, &BeginOptions{
+		TimeOption:     TimeOption{Timestamp: &start},
+		LocalComponent: StreamHeartbeatSpanName,
+		Async:          true,
+	})
+	span.AddAttribute(StreamMessagesKey, s.messages)
+	span.AddAttribute(StreamBytesKey, s.bytes)
+	span.End(&EndOptions{Duration: &duration})
+
+	s.totalMessages += s.messages
+	s.totalBytes += s.bytes
+	s.messages, s.bytes = 0, 0
+	s.lastHeartbeat = now
+}
+
+// End implements End() of tracing.Span. It stops the heartbeats and records the totals of the stream as the
+// StreamMessagesKey and StreamBytesKey attributes of the connection span before ending it. Only the first
+// call ends the connection span, the following calls do nothing.
+func (s *Stream) End(options *EndOptions) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.Span.AddAttribute(StreamMessagesKey, s.totalMessages+s.messages)
+	s.Span.AddAttribute(StreamBytesKey, s.totalBytes+s.bytes)
+	s.mu.Unlock()
+
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+	}
+	s.Span.End(options)
+}
+
+func (s *Stream) wrappedSpan() Span {
+	return s.Span
+}
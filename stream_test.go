@@ -0,0 +1,111 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+func TestStream(t *testing.T) {
+	tracer := mocktracer.New()
+	start := time.Date(2015, 11, 1, 12, 0, 0, 0, time.UTC)
+	clock := tracing.NewFakeClock(start)
+	stream := tracing.NewStream(tracer.BeginTrace("connection", endpoint, nil), &tracing.StreamOptions{Clock: clock})
+
+	message := stream.BeginMessage("message", 100, nil)
+	current, err := tracing.GetSpanFromContext(tracing.ContextWithSpan(context.Background(), message))
+	require.NoError(t, err)
+	assert.Equal(t, message, current)
+	message.End(nil)
+	stream.AddMessage(20)
+	clock.Advance(time.Minute)
+	stream.Heartbeat()
+
+	stream.AddMessage(5)
+	clock.Advance(time.Minute)
+	stream.End(nil)
+	stream.Heartbeat()
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 3)
+	connection, messageSpan, heartbeat := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, "message", messageSpan.Name)
+	assert.Equal(t, connection, messageSpan.Parent)
+	assert.True(t, messageSpan.Options.Async)
+	size, _ := messageSpan.Attribute(tracing.StreamMessageSizeKey)
+	assert.Equal(t, int64(100), size)
+
+	assert.Equal(t, tracing.StreamHeartbeatSpanName, heartbeat.Name)
+	assert.Equal(t, connection, heartbeat.Parent)
+	assert.True(t, heartbeat.Options.Async)
+	assert.True(t, heartbeat.Ended())
+	assert.Equal(t, start, *heartbeat.Options.Timestamp)
+	assert.Equal(t, time.Minute, *heartbeat.EndOptions().Duration)
+	messages, _ := heartbeat.Attribute(tracing.StreamMessagesKey)
+	assert.Equal(t, int64(2), messages)
+	bytes, _ := heartbeat.Attribute(tracing.StreamBytesKey)
+	assert.Equal(t, int64(120), bytes)
+
+	assert.True(t, connection.Ended())
+	messages, _ = connection.Attribute(tracing.StreamMessagesKey)
+	assert.Equal(t, int64(3), messages)
+	bytes, _ = connection.Attribute(tracing.StreamBytesKey)
+	assert.Equal(t, int64(125), bytes)
+}
+
+func TestStreamHeartbeatInterval(t *testing.T) {
+	tracer := mocktracer.New()
+	stream := tracing.NewStream(tracer.BeginTrace("connection", endpoint, nil),
+		&tracing.StreamOptions{HeartbeatInterval: time.Millisecond})
+	stream.AddMessage(10)
+
+	assert.Eventually(t, func() bool {
+		return len(tracer.FinishedSpans()) > 0
+	}, time.Second, time.Millisecond)
+	stream.End(nil)
+	stream.End(nil)
+
+	spans := tracer.FinishedSpans()
+	count := len(spans)
+	time.Sleep(5 * time.Millisecond)
+	assert.Len(t, tracer.FinishedSpans(), count, "heartbeats stop when the stream ends")
+	assert.Equal(t, tracing.StreamHeartbeatSpanName, spans[1].Name)
+	messages, _ := spans[1].Attribute(tracing.StreamMessagesKey)
+	assert.Equal(t, int64(1), messages)
+}
+
+func TestStreamEndTwice(t *testing.T) {
+	tracer, c := newDebugTracer()
+	stream := tracing.NewStream(tracer.BeginTrace("connection", endpoint, nil),
+		&tracing.StreamOptions{HeartbeatInterval: time.Hour})
+	stream.End(nil)
+	stream.End(&tracing.EndOptions{Error: errors.New("late")})
+	assert.Empty(t, c.get(), "the connection span is ended once")
+}
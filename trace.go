@@ -0,0 +1,53 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing
+
+import "context"
+
+// Trace starts a span for a local unit of work, typically a function call, and returns a context storing it
+// together with a function that ends the span. It is designed to be deferred with a pointer to the named error
+// result of the traced function, so that the returned error is recorded in EndOptions.Error:
+//
+//	func (r *repo) Load(ctx context.Context, id string) (err error) {
+//		ctx, done := tracing.Trace(ctx, "repo.Load", nil)
+//		defer done(&err)
+//		...
+//	}
+//
+// The span is started as in StartSpan(). It is marked as local: if options do not set LocalComponent,
+// the span name is used as the component. The done function accepts nil if there is no error to record.
+func Trace(ctx context.Context, spanName string, options *BeginOptions) (context.Context, func(err *error)) {
+	opts := BeginOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.LocalComponent == "" {
+		opts.LocalComponent = spanName
+	}
+	span, ctx := StartSpan(ctx, spanName, &opts)
+	return ctx, func(err *error) {
+		var endOptions *EndOptions
+		if err != nil && *err != nil {
+			endOptions = &EndOptions{Error: *err}
+		}
+		span.End(endOptions)
+	}
+}
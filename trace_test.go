@@ -0,0 +1,81 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-common/opentracing-go"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
+)
+
+var errLoad = errors.New("load failed")
+
+func tracedLoad(ctx context.Context, fail bool) (err error) {
+	ctx, done := tracing.Trace(ctx, "repo.Load", nil)
+	defer done(&err)
+
+	if _, err := tracing.GetSpanFromContext(ctx); err != nil {
+		return err
+	}
+	if fail {
+		return errLoad
+	}
+	return nil
+}
+
+func TestTrace(t *testing.T) {
+	tracer := mocktracer.New()
+	_, ctx := tracing.StartSpanFromContext(context.Background(), tracer, "parent", nil)
+
+	assert.NoError(t, tracedLoad(ctx, false))
+	assert.Equal(t, errLoad, tracedLoad(ctx, true))
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 2)
+	for _, s := range spans {
+		assert.Equal(t, "repo.Load", s.Name)
+		assert.Equal(t, "repo.Load", s.Options.LocalComponent)
+		assert.Equal(t, tracing.SpanKindInternal, s.Kind)
+		assert.Equal(t, tracer.Spans()[0], s.Parent)
+	}
+	assert.NoError(t, spans[0].EndOptions().Error)
+	assert.Equal(t, errLoad, spans[1].EndOptions().Error)
+}
+
+func TestTraceOptions(t *testing.T) {
+	tracer := mocktracer.New()
+	ctx := tracing.ContextWithTracer(context.Background(), tracer)
+
+	options := &tracing.BeginOptions{LocalComponent: "cache"}
+	_, done := tracing.Trace(ctx, "cache.Get", options)
+	done(nil)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "cache", spans[0].Options.LocalComponent)
+	assert.Nil(t, spans[0].Parent)
+	assert.NoError(t, spans[0].EndOptions().Error)
+}
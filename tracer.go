@@ -49,6 +49,10 @@ type Endpoint struct {
 	// IPv4 is 4-byte IP v.4 address of the server represented by this endpoint
 	IPv4 int32
 
+	// IPv6 is 16-byte IP v.6 address of the server represented by this endpoint, or all zeros if unknown.
+	// Reporters should record it in addition to the IPv4 address, see ToZipkinEndpoint().
+	IPv6 [16]byte
+
 	// Port number the server represented by this endpoint is listening to
 	Port uint16
 }
@@ -19,8 +19,8 @@
 package tracing
 
 import (
+	"context"
 	"errors"
-	"golang.org/x/net/context"
 )
 
 // GetSpanFromHeader creates a top-level RPC server-side span. If the provided header value can be parsed
@@ -43,22 +43,66 @@ func GetSpanFromHeader(header string, tracer Tracer, spanName string, endpoint *
 }
 
 const (
+	// CurrentSpanContextKey is the plain string key under which earlier versions of this package stored the span.
+	// GetSpanFromContext still reads spans stored under this key, so that binaries mixing old and new versions
+	// keep working during migration.
+	//
+	// Deprecated: the span is now stored under an unexported key. Use ContextWithSpan and GetSpanFromContext.
 	CurrentSpanContextKey = "tracing.current_span"
 )
 
+// contextKey is unexported to prevent collisions with context keys defined in other packages.
+type contextKey int
+
+const (
+	currentSpanKey contextKey = iota
+	currentTracerKey
+	currentEndpointKey
+)
+
 var (
 	NoCurrentSpanError  = errors.New("No tracing span found in the context")
 	BadCurrentSpanError = errors.New("Tracing span found in the context is of the wrong type")
 )
 
-// ContextWithSpan creates a child context that stores the current span
+// contextAttacher is implemented by spans that attach more than themselves to the contexts they are stored into,
+// such as the runtime/pprof labels of NewProfilerLabelTracer() or the runtime/trace task of NewExecutionTracer().
+type contextAttacher interface {
+	attachToContext(ctx context.Context) context.Context
+}
+
+// spanWrapper is implemented by the span decorators of this package, so that the capabilities of the spans
+// they wrap can be found whatever the order of the decorators.
+type spanWrapper interface {
+	wrappedSpan() Span
+}
+
+// ContextWithSpan creates a child context that stores the current span. Spans created by the tracer
+// from NewProfilerLabelTracer() also add their runtime/pprof labels to the context and to the current
+// goroutine, until they end, and spans created by the tracer from NewExecutionTracer() add their
+// runtime/trace task, so that the regions and logs of the context belong to the task.
 func ContextWithSpan(ctx context.Context, span Span) context.Context {
-	return context.WithValue(ctx, CurrentSpanContextKey, span)
+	for s := span; s != nil; {
+		if a, ok := s.(contextAttacher); ok {
+			ctx = a.attachToContext(ctx)
+		}
+		w, ok := s.(spanWrapper)
+		if !ok {
+			break
+		}
+		s = w.wrappedSpan()
+	}
+	return context.WithValue(ctx, currentSpanKey, span)
 }
 
-// GetSpanFromContext retrieves the current span from the context
+// GetSpanFromContext retrieves the current span from the context. Spans stored under the legacy
+// CurrentSpanContextKey are found as well.
 func GetSpanFromContext(ctx context.Context) (Span, error) {
-	if val := ctx.Value(CurrentSpanContextKey); val == nil {
+	val := ctx.Value(currentSpanKey)
+	if val == nil {
+		val = ctx.Value(CurrentSpanContextKey)
+	}
+	if val == nil {
 		return nil, NoCurrentSpanError
 	} else if span, ok := val.(Span); !ok {
 		return nil, BadCurrentSpanError
@@ -66,3 +110,67 @@ func GetSpanFromContext(ctx context.Context) (Span, error) {
 		return span, nil
 	}
 }
+
+// ContextWithTracer creates a child context that stores the tracer. Processes running several tracers, e.g. one
+// per service endpoint, use it to let downstream code find the tracer that created the spans in the context.
+func ContextWithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, currentTracerKey, tracer)
+}
+
+// TracerFromContext retrieves the tracer stored in the context, or returns GlobalTracer() if there is none.
+func TracerFromContext(ctx context.Context) Tracer {
+	if tracer, ok := ctx.Value(currentTracerKey).(Tracer); ok {
+		return tracer
+	}
+	return GlobalTracer()
+}
+
+// ContextWithEndpoint creates a child context that stores the service endpoint. It is used as the service of
+// new traces started by the context helpers, so that processes running several services, e.g. one tracer
+// per service endpoint, report the traces of each under the right service.
+func ContextWithEndpoint(ctx context.Context, endpoint *Endpoint) context.Context {
+	return context.WithValue(ctx, currentEndpointKey, endpoint)
+}
+
+// EndpointFromContext retrieves the service endpoint stored in the context, or returns nil if there is none,
+// in which case new traces are started without a service endpoint, letting the tracer use its own.
+func EndpointFromContext(ctx context.Context) *Endpoint {
+	endpoint, _ := ctx.Value(currentEndpointKey).(*Endpoint)
+	return endpoint
+}
+
+// GetHeaderFromContext serializes the ID of the current span in the context, using the string pickler of
+// the tracer from TracerFromContext(). The result can be passed to GetSpanFromHeader() by the receiving service.
+func GetHeaderFromContext(ctx context.Context) (string, error) {
+	span, err := GetSpanFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return TracerFromContext(ctx).GetStringPickler().ToString(span.SpanID()), nil
+}
+
+// StartSpanFromContext starts a span for a unit of work and returns it together with a child context storing it.
+// If the context holds a span, the new span is started as its child via BeginChildSpan(), and the tracer stored
+// in the context is kept, since it is the tracer that created the parent span; if the context stores no tracer,
+// the returned context stores the given one. Otherwise a new trace is started via tracer.BeginTrace() with the
+// service endpoint from EndpointFromContext(), and the returned context also stores the tracer, see
+// ContextWithTracer(). A value of the wrong type stored in place of the span is ignored and replaced in the
+// returned context.
+func StartSpanFromContext(ctx context.Context, tracer Tracer, spanName string, options *BeginOptions) (Span, context.Context) {
+	var span Span
+	if parent, err := GetSpanFromContext(ctx); err == nil {
+		span = parent.BeginChildSpan(spanName, options)
+		if _, ok := ctx.Value(currentTracerKey).(Tracer); !ok {
+			ctx = ContextWithTracer(ctx, tracer)
+		}
+	} else {
+		span = tracer.BeginTrace(spanName, EndpointFromContext(ctx), options)
+		ctx = ContextWithTracer(ctx, tracer)
+	}
+	return span, ContextWithSpan(ctx, span)
+}
+
+// StartSpan is the same as StartSpanFromContext(), using the tracer from TracerFromContext().
+func StartSpan(ctx context.Context, spanName string, options *BeginOptions) (Span, context.Context) {
+	return StartSpanFromContext(ctx, TracerFromContext(ctx), spanName, options)
+}
@@ -20,10 +20,13 @@
 package tracing_test
 
 import (
+	"context"
+	"testing"
+
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/uber-common/opentracing-go"
-	"golang.org/x/net/context"
-	"testing"
+	"github.com/uber-common/opentracing-go/internal/mocktracer"
 )
 
 var endpoint = &tracing.Endpoint{ServiceName: "test-service"}
@@ -68,4 +71,128 @@ func TestContextOps(t *testing.T) {
 	span2, err := tracing.GetSpanFromContext(ctx)
 	assert.NoError(t, err)
 	assert.Equal(t, span, span2)
+
+	// the span is not visible under the legacy key
+	assert.Nil(t, ctx.Value(tracing.CurrentSpanContextKey))
+
+	// overwriting the legacy key does not hide the span
+	ctx = context.WithValue(ctx, tracing.CurrentSpanContextKey, tracer)
+	span2, err = tracing.GetSpanFromContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, span, span2)
+}
+
+func TestContextLegacyKey(t *testing.T) {
+	span := tracing.NewNoopTracer().BeginTrace("test-span", endpoint, nil)
+
+	// span stored by an older version of the package
+	ctx := context.WithValue(context.Background(), tracing.CurrentSpanContextKey, span)
+	span2, err := tracing.GetSpanFromContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, span, span2)
+}
+
+func TestStartSpanFromContext(t *testing.T) {
+	tracer := mocktracer.New()
+
+	root, ctx := tracing.StartSpanFromContext(context.Background(), tracer, "root", nil)
+	span, err := tracing.GetSpanFromContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, root, span)
+
+	child, childCtx := tracing.StartSpanFromContext(ctx, tracer, "child", &tracing.BeginOptions{LocalComponent: "lc"})
+	span, err = tracing.GetSpanFromContext(childCtx)
+	assert.NoError(t, err)
+	assert.Equal(t, child, span)
+
+	// a garbage value under the legacy key starts a new trace
+	badCtx := context.WithValue(context.Background(), tracing.CurrentSpanContextKey, "garbage")
+	other, otherCtx := tracing.StartSpanFromContext(badCtx, tracer, "other", nil)
+	span, err = tracing.GetSpanFromContext(otherCtx)
+	assert.NoError(t, err)
+	assert.Equal(t, other, span)
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 3)
+	assert.Nil(t, spans[0].Parent)
+	assert.Equal(t, spans[0], spans[1].Parent)
+	assert.Equal(t, "lc", spans[1].Options.LocalComponent)
+	assert.Nil(t, spans[2].Parent)
+	assert.NotEqual(t, spans[0].ID.TraceID(), spans[2].ID.TraceID())
+}
+
+func TestStartSpanFromContextKeepsParentTracer(t *testing.T) {
+	tracer := mocktracer.New()
+	_, ctx := tracing.StartSpanFromContext(context.Background(), tracer, "root", nil)
+
+	child, childCtx := tracing.StartSpanFromContext(ctx, tracing.NewNoopTracer(), "child", nil)
+	assert.Equal(t, tracing.Tracer(tracer), tracing.TracerFromContext(childCtx))
+	header, err := tracing.GetHeaderFromContext(childCtx)
+	require.NoError(t, err)
+	assert.Equal(t, tracer.GetStringPickler().ToString(child.SpanID()), header)
+}
+
+func TestStartSpanFromContextStoresTracer(t *testing.T) {
+	tracer := mocktracer.New()
+	ctx := tracing.ContextWithSpan(context.Background(), tracer.BeginTrace("root", endpoint, nil))
+
+	child, childCtx := tracing.StartSpanFromContext(ctx, tracer, "child", nil)
+	assert.Equal(t, tracing.Tracer(tracer), tracing.TracerFromContext(childCtx))
+	header, err := tracing.GetHeaderFromContext(childCtx)
+	require.NoError(t, err)
+	assert.Equal(t, tracer.GetStringPickler().ToString(child.SpanID()), header)
+}
+
+func TestStartSpan(t *testing.T) {
+	tracer := mocktracer.New()
+	tracing.SetGlobalTracer(tracer)
+	defer tracing.SetGlobalTracer(tracing.NewNoopTracer())
+
+	span, ctx := tracing.StartSpan(context.Background(), "root", nil)
+	assert.NotNil(t, ctx)
+	span.End(nil)
+	require.Len(t, tracer.FinishedSpans(), 1)
+	assert.Equal(t, "root", tracer.FinishedSpans()[0].Name)
+}
+
+func TestContextWithTracer(t *testing.T) {
+	assert.Equal(t, tracing.GlobalTracer(), tracing.TracerFromContext(context.Background()))
+
+	tracer1, tracer2 := mocktracer.New(), mocktracer.New()
+	ctx := tracing.ContextWithTracer(context.Background(), tracer1)
+	assert.Equal(t, tracer1, tracing.TracerFromContext(ctx))
+
+	_, err := tracing.GetHeaderFromContext(ctx)
+	assert.Equal(t, tracing.NoCurrentSpanError, err)
+
+	// StartSpan uses the tracer from the context
+	root, rootCtx := tracing.StartSpan(ctx, "root", nil)
+	require.Len(t, tracer1.Spans(), 1)
+	header, err := tracing.GetHeaderFromContext(rootCtx)
+	assert.NoError(t, err)
+	assert.Equal(t, tracer1.GetStringPickler().ToString(root.SpanID()), header)
+
+	// StartSpanFromContext with an explicit tracer stores it for downstream code
+	_, serverCtx := tracing.StartSpanFromContext(context.Background(), tracer2, "server", nil)
+	assert.Equal(t, tracer2, tracing.TracerFromContext(serverCtx))
+	tracing.StartSpan(serverCtx, "child", nil)
+	assert.Len(t, tracer2.Spans(), 2)
+	assert.Len(t, tracer1.Spans(), 1)
+}
+
+func TestContextWithEndpoint(t *testing.T) {
+	assert.Nil(t, tracing.EndpointFromContext(context.Background()))
+
+	tracer := mocktracer.New()
+	tracing.StartSpanFromContext(context.Background(), tracer, "default", nil)
+	ctx := tracing.ContextWithEndpoint(context.Background(), endpoint)
+	assert.Equal(t, endpoint, tracing.EndpointFromContext(ctx))
+	_, rootCtx := tracing.StartSpanFromContext(ctx, tracer, "root", nil)
+	tracing.StartSpan(rootCtx, "child", nil)
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 3)
+	assert.Nil(t, spans[0].Service)
+	assert.Equal(t, endpoint, spans[1].Service)
+	assert.Equal(t, endpoint, spans[2].Service)
 }
@@ -18,6 +18,8 @@
 
 package tracing
 
+import "net"
+
 // ZipkinCompatibleTracer is a tracer that represents trace ID as a 4-tuple similar to Zipkin.
 type ZipkinCompatibleTracer interface {
 	// CreateSpanID instantiates ZipkinSpanID from 4 values. It is not meant for creating brand new IDs
@@ -43,3 +45,62 @@ type ZipkinSpanID interface {
 	// IsSampled returns whether this trace was chosen for permanent storage by the sampling mechanism of the tracer.
 	IsSampled() bool
 }
+
+// ZipkinKind returns the Zipkin span kind ("CLIENT", "SERVER", "PRODUCER" or "CONSUMER") corresponding to the
+// given span kind. Zipkin has no kind for local spans, so an empty string is returned for internal spans.
+func ZipkinKind(kind SpanKind) string {
+	switch kind {
+	case SpanKindClient:
+		return "CLIENT"
+	case SpanKindServer:
+		return "SERVER"
+	case SpanKindProducer:
+		return "PRODUCER"
+	case SpanKindConsumer:
+		return "CONSUMER"
+	default:
+		return ""
+	}
+}
+
+// ZipkinCoreAnnotations returns the Zipkin v1 core annotations that a reporter should record at the beginning
+// and at the end of a span of the given kind, e.g. "cs" and "cr" for client spans. Messaging spans only have
+// a start annotation, and internal spans have none, in which case empty strings are returned.
+func ZipkinCoreAnnotations(kind SpanKind) (begin string, end string) {
+	switch kind {
+	case SpanKindClient:
+		return "cs", "cr"
+	case SpanKindServer:
+		return "sr", "ss"
+	case SpanKindProducer:
+		return "ms", ""
+	case SpanKindConsumer:
+		return "mr", ""
+	default:
+		return "", ""
+	}
+}
+
+// ZipkinEndpoint is the representation of an Endpoint in the Zipkin v2 data model.
+type ZipkinEndpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Port        uint16 `json:"port,omitempty"`
+}
+
+// ToZipkinEndpoint converts the endpoint to the form reported to Zipkin, with both the IPv4 and the IPv6
+// address in text form if they are known. It returns nil for a nil endpoint.
+func ToZipkinEndpoint(endpoint *Endpoint) *ZipkinEndpoint {
+	if endpoint == nil {
+		return nil
+	}
+	z := &ZipkinEndpoint{ServiceName: endpoint.ServiceName, Port: endpoint.Port}
+	if endpoint.IPv4 != 0 {
+		z.IPv4 = (&Endpoint{IPv4: endpoint.IPv4}).IP().String()
+	}
+	if endpoint.hasIPv6() {
+		z.IPv6 = net.IP(endpoint.IPv6[:]).String()
+	}
+	return z
+}